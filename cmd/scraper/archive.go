@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/archive"
+	"github.com/ysonC/multi-stocks-download/internal/config"
+	"github.com/ysonC/multi-stocks-download/internal/report"
+)
+
+func runArchive(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("archive", cfg)
+	dateFlag := fs.String("date", time.Now().Format("2006-01-02"), "date used to name the archive")
+	pruneOnly := fs.Bool("prune", false, "only prune old snapshots by the retention policy, without archiving")
+	fs.StringVar(&cfg.Dirs.Archive, "out", cfg.Dirs.Archive, "shorthand for -archive-dir")
+	fs.StringVar(
+		&cfg.Archive.Format,
+		"format",
+		cfg.Archive.Format,
+		"archive format: "+strings.Join(archive.Formats, ", ")+" (env SCRAPER_ARCHIVE_FORMAT)",
+	)
+	fs.IntVar(&cfg.Archive.Keep.Daily, "keep-daily", cfg.Archive.Keep.Daily, "keep the newest N daily snapshots")
+	fs.IntVar(
+		&cfg.Archive.Keep.Weekly,
+		"keep-weekly",
+		cfg.Archive.Keep.Weekly,
+		"also keep the newest snapshot of each of the last N weeks",
+	)
+	fs.IntVar(
+		&cfg.Archive.Keep.Monthly,
+		"keep-monthly",
+		cfg.Archive.Keep.Monthly,
+		"also keep the newest snapshot of each of the last N months",
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper archive [options]")
+		fmt.Fprintln(fs.Output(), `
+Archive data/downloaded_stock/ and data/final_output/ into
+<out>/<date>/raw-<date>.<format> and combined-<date>.<format>, each with a
+manifest.json listing the files and the latest run report. Then remove the
+snapshots the retention policy (-keep-*) does not keep; with no -keep-*
+every snapshot is kept.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), `
+Examples:
+  # Today's snapshot as tar.zst, keeping a week of dailies and a year of monthlies
+  scraper archive -format=tar.zst -keep-daily=7 -keep-monthly=12`)
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+	if _, err := time.Parse("2006-01-02", *dateFlag); err != nil {
+		return fmt.Errorf("invalid -date %q: want YYYY-MM-DD", *dateFlag)
+	}
+
+	if *pruneOnly {
+		return pruneArchives(cfg)
+	}
+	run, err := os.ReadFile(filepath.Join(cfg.Dirs.Resolve().Report, report.LatestFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read latest run report: %w", err)
+	}
+	if !json.Valid(run) {
+		run = nil
+	}
+	return archiveData(cfg, *dateFlag, run)
+}
+
+// archiveData snapshots the downloaded and combined data under date, with run
+// as the run report of each manifest, then prunes old snapshots.
+func archiveData(cfg *config.Config, date string, run json.RawMessage) error {
+	dirs := cfg.Dirs.Resolve()
+	files, err := archive.Snapshot(dirs.Archive, archive.Options{
+		Date:     date,
+		Format:   cfg.Archive.Format,
+		RawDir:   dirs.Download,
+		FinalDir: dirs.FinalOutput,
+		Run:      run,
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		slog.Info("Created archive.", "file", f)
+	}
+	return pruneArchives(cfg)
+}
+
+// pruneArchives removes the snapshots the configured retention policy does
+// not keep.
+func pruneArchives(cfg *config.Config) error {
+	removed, err := archive.Prune(cfg.Dirs.Resolve().Archive, cfg.Archive.Retention())
+	for _, dir := range removed {
+		slog.Info("Removed archive.", "dir", dir)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune archives: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ysonC/multi-stocks-download/internal/flow"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+func runCombine(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("combine", cfg)
+	fs.StringVar(
+		&cfg.Output.CombinedFormat,
+		"format",
+		cfg.Output.Combined(),
+		"file format of final_output/: "+strings.Join(storage.CombinedFormats, ", "),
+	)
+	fs.StringVar(
+		&cfg.Output.HeaderLang,
+		"header-lang",
+		cfg.Output.HeaderLang,
+		"header rows of combined CSV/XLSX outputs: "+strings.Join(storage.HeaderLangs, ", "),
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper combine [options] [stock ... | -]")
+		fmt.Fprintln(fs.Output(), `
+Combine the downloaded data of the given stocks (default: every stock in
+data/downloaded_stock/) into data/final_output/ without scraping.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), `
+Examples:
+  # Rebuild every combined CSV with English headers
+  scraper combine -header-lang=en
+
+  # Rebuild the XLSX workbooks of two stocks
+  scraper combine -format=xlsx 2330 2317`)
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+
+	dirs := cfg.Dirs.Resolve()
+	stocks, err := stockArgs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(stocks) == 0 {
+		stocks, err = storage.ListStocks(dirs.Download)
+		if err != nil {
+			return fmt.Errorf("failed to list downloaded stocks: %w", err)
+		}
+	}
+	if len(stocks) == 0 {
+		slog.Info("No downloaded stocks found. Exiting.", "dir", dirs.Download)
+		return nil
+	}
+
+	if err := flow.SetupDirectories(dirs.FinalOutput); err != nil {
+		return err
+	}
+	slog.Info("Combining stocks.", "stocks", len(stocks), "dir", dirs.FinalOutput)
+	results, err := storage.CombineSuccessfulStocks(
+		stocks,
+		dirs.Download,
+		dirs.FinalOutput,
+		cfg.Output.Combined(),
+		cfg.Output.HeaderLang,
+	)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stock(s) failed to combine", failed, len(results))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ysonC/multi-stocks-download/internal/config"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/progress"
+)
+
+// configPath returns the -config value in args, falling back to
+// $SCRAPER_CONFIG. It is read before the flag set is parsed so the file can
+// supply the flag defaults.
+func configPath(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(config.EnvPath)
+}
+
+// loadConfig loads the config file named in args and applies the environment
+// overrides. Flags parsed afterwards take precedence over both.
+func loadConfig(args []string) (*config.Config, error) {
+	cfg, err := config.Load(configPath(args))
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// newFlagSet returns a flag set for the named command with the flags every
+// command shares: -config, whose value has already been read by loadConfig,
+// and the directory flags, bound to cfg.Dirs.
+func newFlagSet(name string, cfg *config.Config) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.String("config", "", "YAML configuration file (default $"+config.EnvPath+")")
+
+	dirs := &cfg.Dirs
+	fs.StringVar(&dirs.Data, "data-dir", dirs.Data, "root directory of all data (env SCRAPER_DATA_DIR)")
+	dirFlags := []struct {
+		name   string
+		dir    *string
+		subdir string
+		usage  string
+	}{
+		{"input-dir", &dirs.Input, config.InputSubdir, "stock list inputs"},
+		{"download-dir", &dirs.Download, config.DownloadSubdir, "raw per-stock downloads"},
+		{"final-output-dir", &dirs.FinalOutput, config.FinalOutputSubdir, "combined per-stock outputs"},
+		{"failed-dir", &dirs.Failed, config.FailedSubdir, "the failed stock list"},
+		{"export-dir", &dirs.Export, config.ExportSubdir, "panel exports"},
+		{"archive-dir", &dirs.Archive, config.ArchiveSubdir, "dated archives"},
+		{"report-dir", &dirs.Report, config.ReportSubdir, "run reports"},
+	}
+	for _, f := range dirFlags {
+		env := "SCRAPER_" + strings.ToUpper(strings.ReplaceAll(f.name, "-", "_"))
+		fs.StringVar(
+			f.dir,
+			f.name,
+			*f.dir,
+			fmt.Sprintf("directory of %s (default <data-dir>/%s, env %s)", f.usage, f.subdir, env),
+		)
+	}
+
+	fs.StringVar(
+		&cfg.Log.Level,
+		"log-level",
+		cfg.Log.Level,
+		"minimum log level: "+strings.Join(logging.Levels, ", ")+" (env SCRAPER_LOG_LEVEL)",
+	)
+	fs.StringVar(
+		&cfg.Log.Format,
+		"log-format",
+		cfg.Log.Format,
+		"log format: "+strings.Join(logging.Formats, ", ")+" (env SCRAPER_LOG_FORMAT)",
+	)
+	fs.StringVar(&cfg.Log.File, "log-file", cfg.Log.File, "append the log to this file instead of stderr (env SCRAPER_LOG_FILE)")
+	return fs
+}
+
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(`unknown config subcommand, want "scraper config print"`)
+	}
+	args = args[1:]
+
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("config print", cfg)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper config print [options]")
+		fmt.Fprintln(fs.Output(), `
+Print the effective configuration: the built-in defaults overlaid with the
+config file, the SCRAPER_* environment variables and the flags. The output is
+a valid config file, so it is a convenient starting point for writing one.
+The API token, webhook header values and the path of webhook URLs are printed
+as REDACTED.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+
+	data, err := cfg.Redacted().YAML()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// addGroupFlag registers -group, which limits the input stock list to the
+// given groups.
+func addGroupFlag(fs *flag.FlagSet, cfg *config.Config) {
+	fs.Func(
+		"group",
+		"comma-separated groups; only input stocks tagged with one of them are used (env SCRAPER_GROUPS)",
+		func(v string) error {
+			cfg.Groups = config.SplitList(v)
+			return nil
+		},
+	)
+}
+
+// closeLog closes the log file opened by parseWithConfig; main calls it once
+// the command returns.
+var closeLog = func() error { return nil }
+
+// console is standard error, shared by the log and the progress bar.
+var console = progress.NewConsole(os.Stderr)
+
+// parseWithConfig parses args into fs, whose flags are bound to cfg,
+// validates the resulting configuration and sets up logging from it.
+func parseWithConfig(fs *flag.FlagSet, cfg *config.Config, args []string) error {
+	fs.Parse(args)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	closer, err := logging.Setup(cfg.Log.Level, cfg.Log.Format, cfg.Log.File, console)
+	if err != nil {
+		return err
+	}
+	closeLog = closer
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/archive"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+func runDiff(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("diff", cfg)
+	datasetsFlag := fs.String(
+		"datasets",
+		strings.Join(storage.DatasetNames(), ","),
+		"comma-separated datasets to compare",
+	)
+	outFlag := fs.String(
+		"out",
+		"",
+		`CSV file of changed cells, "-" for stdout (default <report-dir>/diff-<time>.csv)`,
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper diff [options] <old> [new]")
+		fmt.Fprintln(fs.Output(), `
+Compare two snapshots of the downloaded data and report, per stock and
+dataset, the rows added, removed and changed from old to new. Each snapshot
+is a download directory or a raw archive written by "scraper archive"; new
+defaults to data/downloaded_stock/. Every changed cell is written to a CSV
+with its stock, dataset, period, column, old and new value.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), `
+Examples:
+  # What changed since the archive of 10 January
+  scraper diff data/archives/2025-01-10/raw-2025-01-10.zip
+
+  # Restated cash flows between two archives
+  scraper diff -datasets=cashflow -out=- old.zip new.zip`)
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+
+	dirs := cfg.Dirs.Resolve()
+	paths := fs.Args()
+	switch len(paths) {
+	case 1:
+		paths = append(paths, dirs.Download)
+	case 2:
+	default:
+		fs.Usage()
+		return fmt.Errorf("diff needs one or two snapshots, got %d", len(paths))
+	}
+
+	var snapshots [2]string
+	for i, path := range paths {
+		dir, cleanup, err := archive.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open snapshot %s: %w", path, err)
+		}
+		defer cleanup()
+		snapshots[i] = dir
+	}
+
+	diff, err := storage.DiffSnapshots(snapshots[0], snapshots[1], strings.Split(*datasetsFlag, ","))
+	if err != nil {
+		return err
+	}
+
+	summary := io.Writer(os.Stdout)
+	out := *outFlag
+	switch out {
+	case "-":
+		summary = os.Stderr
+		if err := diff.WriteCellsCSV(os.Stdout); err != nil {
+			return err
+		}
+	case "":
+		out = filepath.Join(dirs.Report, "diff-"+time.Now().Format("20060102-150405")+".csv")
+		fallthrough
+	default:
+		if err := writeDiffCells(diff, out); err != nil {
+			return fmt.Errorf("failed to write changed cells: %w", err)
+		}
+	}
+
+	printDiff(summary, diff)
+	t := diff.Totals()
+	attrs := []any{"added", t.Added, "removed", t.Removed, "changed", t.Changed, "cells", t.Cells}
+	if out != "-" {
+		attrs = append(attrs, "file", out)
+	}
+	slog.Info("Diff finished.", attrs...)
+	return nil
+}
+
+// writeDiffCells writes the changed cells of diff to a CSV file at path.
+func writeDiffCells(diff *storage.SnapshotDiff, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := diff.WriteCellsCSV(file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// printDiff prints one line per differing stock and dataset, and the totals.
+func printDiff(out io.Writer, diff *storage.SnapshotDiff) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STOCK\tDATASET\tADDED\tREMOVED\tCHANGED\tCELLS")
+	for _, d := range diff.Datasets {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\n", d.Stock, d.Dataset, d.Added, d.Removed, d.Changed, d.Cells)
+	}
+	t := diff.Totals()
+	fmt.Fprintf(w, "TOTAL\t\t%d\t%d\t%d\t%d\n", t.Added, t.Removed, t.Changed, t.Cells)
+	w.Flush()
+}
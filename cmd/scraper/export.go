@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+func runExport(args []string) error {
+	start := time.Now()
+
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("export", cfg)
+	layoutFlag := fs.String("layout", "long", "panel layout: long (stock_id, period, column, value) or wide")
+	formatFlag := fs.String("format", "csv", "output format: csv or parquet")
+	datasetsFlag := fs.String(
+		"datasets",
+		strings.Join(storage.DatasetNames(), ","),
+		"comma-separated datasets to export",
+	)
+	fs.StringVar(&cfg.Dirs.Download, "in", cfg.Dirs.Download, "shorthand for -download-dir")
+	fs.StringVar(&cfg.Dirs.Export, "out", cfg.Dirs.Export, "shorthand for -export-dir")
+
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper export [options]")
+		fmt.Fprintln(fs.Output(), `
+Write one file per dataset covering every downloaded stock.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), `
+Examples:
+  # Tidy long-format CSV for every dataset
+  scraper export
+
+  # Wide Parquet panel of weekly prices and PER
+  scraper export -layout=wide -format=parquet -datasets=per,stockdata`)
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+
+	dirs := cfg.Dirs.Resolve()
+	datasets := strings.Split(*datasetsFlag, ",")
+	files, err := storage.ExportPanels(
+		dirs.Download,
+		dirs.Export,
+		*layoutFlag,
+		*formatFlag,
+		datasets,
+	)
+	if err != nil {
+		return err
+	}
+
+	slog.Info(
+		"Export finished.",
+		"files", len(files),
+		"dir", dirs.Export,
+		logging.KeyDuration, time.Since(start),
+	)
+	return nil
+}
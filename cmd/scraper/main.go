@@ -1,141 +1,77 @@
 package main
 
 import (
-	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
-	"github.com/ysonC/multi-stocks-download/internal/flow"
-	"github.com/ysonC/multi-stocks-download/internal/scraper"
-	"github.com/ysonC/multi-stocks-download/internal/storage"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
 )
 
-const dataDir = "data"
-
-var (
-	inputDir       = filepath.Join(dataDir, "input_stock")
-	downloadDir    = filepath.Join(dataDir, "downloaded_stock")
-	finalOutputDir = filepath.Join(dataDir, "final_output")
-	failedDir      = filepath.Join(dataDir, "failed_stock")
-)
-
-func main() {
-	log.Println("Starting scraper application...")
-	start := time.Now()
-
-	maxWorkersFlag := flag.Int("workers", 5, "maximum number of concurrent workers (default 5)")
-	flag.IntVar(maxWorkersFlag, "w", 5, "shorthand for -workers")
-	rerunFailedFlag := flag.Bool(
-		"rerun-failed",
-		false,
-		"rerun only failed stocks from the previous run",
-	)
-	flag.BoolVar(rerunFailedFlag, "rf", false, "shorthand for -rerun-failed")
-
-	startDateFlag := flag.String(
-		"start",
-		"",
-		"start date in YYYY-MM-DD (default 1965-01-01 when omitted together with -end)",
-	)
-	endDateFlag := flag.String(
-		"end",
-		"",
-		"end date in YYYY-MM-DD (default today when omitted together with -start)",
-	)
+// command is one subcommand of the scraper binary.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
 
-	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [options]\n\n", os.Args[0])
-		fmt.Fprintln(flag.CommandLine.Output(), "Options:")
-		flag.PrintDefaults()
-		fmt.Fprintln(flag.CommandLine.Output(), `
-Examples:
-  # Default: 10 workers, full date range
-  scraper
+var commands = []command{
+	{"scrape", "scrape every stock in the input list, then combine the results", runScrape},
+	{"rerun", "scrape only the stocks that failed in the previous run", runRerun},
+	{"serve", "run scheduled scrape jobs and the HTTP API until interrupted", runServe},
+	{"combine", "combine downloaded data into final outputs without scraping", runCombine},
+	{"export", "export long or wide panels covering every stock", runExport},
+	{"status", "show how fresh each stock's downloaded data is", runStatus},
+	{"validate", "check downloaded data for missing datasets and quality issues", runValidate},
+	{"diff", "compare two snapshots of downloaded data", runDiff},
+	{"archive", "snapshot downloaded and combined data into dated archives and prune old ones", runArchive},
+	{"universe", "fetch the listed-company universe and compare it with the input list", runUniverse},
+	{"config", "print the effective configuration", runConfig},
+}
 
-  # Custom workers and date range
-  scraper -workers=20 -start=2020-01-01 -end=2024-12-31`)
+func usage() {
+	out := os.Stderr
+	fmt.Fprintf(out, "Usage: %s <command> [options]\n\n", filepath.Base(os.Args[0]))
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
+	fmt.Fprintln(out, `
+Without a command, scrape is run, so existing invocations keep working.
+Every command accepts -config=<file.yaml> (or $SCRAPER_CONFIG); settings
+come from the defaults, the file, SCRAPER_* environment variables and flags,
+each overriding the previous. Logging is set with -log-level, -log-format
+(text or json) and -log-file.
+Run "scraper <command> -h" for the options of a command.`)
+}
 
-	flag.Parse()
-
-	maxWorkers := *maxWorkersFlag
-	rerunFailed := *rerunFailedFlag
-	if maxWorkers <= 0 {
-		log.Fatalf("Invalid workers value %d, must be > 0", maxWorkers)
+func main() {
+	name, args := "scrape", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
-
-	var startDate, endDate string
-	if *startDateFlag == "" && *endDateFlag == "" {
-		startDate = "1965-01-01"
-		endDate = time.Now().Format("2006-01-02")
-	} else if *startDateFlag != "" && *endDateFlag != "" {
-		startDate = *startDateFlag
-		endDate = *endDateFlag
-	} else {
-		log.Fatal("Both -start and -end must be provided together, or neither for max range")
+	if name == "help" {
+		usage()
+		return
 	}
 
-	flow.SetupDirectories(inputDir, downloadDir, finalOutputDir, failedDir)
-
-	var stocks []string
-	if rerunFailed {
-		var err error
-		stocks, err = storage.LoadFailedStocks(failedDir)
-		if err != nil {
-			log.Fatalf("Failed to load failed stocks: %v", err)
-		}
-		if len(stocks) == 0 {
-			log.Println("No recorded failed stocks to rerun. Exiting.")
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(args)
+			if err != nil {
+				slog.Error("Command failed.", "command", name, logging.KeyError, err)
+			}
+			closeLog()
+			if err != nil {
+				os.Exit(1)
+			}
 			return
 		}
-		log.Printf("Rerunning %d previously failed stock(s).", len(stocks))
-	} else {
-		stocks = flow.GetStockNumbers(inputDir)
-	}
-
-	pw := flow.SetupPlaywright()
-	defer pw.Stop()
-
-	scraperTypes := []string{"per", "stockdata", "monthlyrevenue", "cashflow", "equity"}
-
-	downloadStart := time.Now()
-	successStocks, errorStocks := scraper.ScrapeAllStocks(
-		pw,
-		stocks,
-		scraperTypes,
-		startDate,
-		endDate,
-		maxWorkers,
-		downloadDir,
-	)
-	log.Printf("Download process completed in %s", time.Since(downloadStart))
-
-	successCount := len(successStocks)
-	errorCount := len(errorStocks)
-
-	if err := storage.SaveFailedStocks(failedDir, errorStocks); err != nil {
-		log.Fatalf("Failed to write failed stocks list: %v", err)
-	}
-
-	err := storage.CombineSuccessfulStocks(successStocks, downloadDir, finalOutputDir)
-	if err != nil {
-		log.Fatalf("Error combining successful stocks: %v", err)
-	}
-
-	failedSummary := ""
-	if errorCount > 0 {
-		failedSummary = " Failed stocks: " + strings.Join(errorStocks, ", ") + "."
 	}
 
-	log.Printf(
-		"Run summary: %d stocks succeeded, %d failed.%s Total duration: %s.",
-		successCount,
-		errorCount,
-		failedSummary,
-		time.Since(start),
-	)
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
 }
@@ -0,0 +1,668 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/checkpoint"
+	"github.com/ysonC/multi-stocks-download/internal/config"
+	"github.com/ysonC/multi-stocks-download/internal/flow"
+	"github.com/ysonC/multi-stocks-download/internal/lock"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/metrics"
+	"github.com/ysonC/multi-stocks-download/internal/notify"
+	"github.com/ysonC/multi-stocks-download/internal/progress"
+	"github.com/ysonC/multi-stocks-download/internal/report"
+	"github.com/ysonC/multi-stocks-download/internal/scraper"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+// newScrapeFlagSet returns the flags shared by the scrape and rerun commands,
+// bound to cfg so that flags override the config file and environment.
+func newScrapeFlagSet(name string, cfg *config.Config) *flag.FlagSet {
+	fs := newFlagSet(name, cfg)
+
+	fs.IntVar(&cfg.Workers, "workers", cfg.Workers, "maximum number of concurrent workers")
+	fs.IntVar(&cfg.Workers, "w", cfg.Workers, "shorthand for -workers")
+	fs.StringVar(
+		&cfg.StartDate,
+		"start",
+		cfg.StartDate,
+		"start date in YYYY-MM-DD (default 1965-01-01 when omitted together with -end)",
+	)
+	fs.StringVar(
+		&cfg.EndDate,
+		"end",
+		cfg.EndDate,
+		"end date in YYYY-MM-DD (default today when omitted together with -start)",
+	)
+	fs.Func(
+		"scrapers",
+		"comma-separated datasets to scrape (default "+strings.Join(cfg.Scrapers, ",")+")",
+		func(v string) error {
+			cfg.Scrapers = config.SplitList(v)
+			return nil
+		},
+	)
+	fs.StringVar(
+		&cfg.Output.Store,
+		"store",
+		cfg.Output.Store,
+		"where scraped data is stored: csv (files in downloaded_stock/), sqlite, or both",
+	)
+	fs.StringVar(
+		&cfg.Output.DB,
+		"db",
+		cfg.Output.DB,
+		"path of the SQLite database used by -store=sqlite|both (default <data-dir>/"+config.DBFile+")",
+	)
+	fs.StringVar(
+		&cfg.Output.Format,
+		"format",
+		cfg.Output.Format,
+		"file format of downloaded_stock/ and final_output/: "+strings.Join(storage.FileFormats, ", "),
+	)
+	fs.StringVar(
+		&cfg.Output.CombinedFormat,
+		"combined-format",
+		cfg.Output.CombinedFormat,
+		"file format of final_output/ when it should differ from -format: "+
+			strings.Join(storage.CombinedFormats, ", "),
+	)
+	fs.StringVar(
+		&cfg.Output.HeaderLang,
+		"header-lang",
+		cfg.Output.HeaderLang,
+		"header rows of combined CSV/XLSX outputs: "+strings.Join(storage.HeaderLangs, ", "),
+	)
+	fs.Func(
+		"order",
+		"comma-separated task order policies, the first deciding: "+strings.Join(config.Orders, ", ")+
+			" (default "+strings.Join(cfg.Order, ",")+", env SCRAPER_ORDER)",
+		func(v string) error {
+			cfg.Order = config.SplitList(v)
+			return nil
+		},
+	)
+	fs.Func(
+		"priority-groups",
+		"comma-separated input groups scraped first by -order=priority, most urgent first (env SCRAPER_PRIORITY_GROUPS)",
+		func(v string) error {
+			cfg.PriorityGroups = config.SplitList(v)
+			return nil
+		},
+	)
+	fs.BoolVar(
+		&cfg.Output.ReportHTML,
+		"report-html",
+		cfg.Output.ReportHTML,
+		"also write the run report as HTML (env SCRAPER_REPORT_HTML)",
+	)
+	fs.BoolVar(
+		&cfg.Archive.AfterScrape,
+		"archive",
+		cfg.Archive.AfterScrape,
+		"archive the data after the run, as the archive command does (env SCRAPER_ARCHIVE_AFTER_SCRAPE)",
+	)
+	fs.StringVar(
+		&cfg.MetricsAddr,
+		"metrics-addr",
+		cfg.MetricsAddr,
+		"serve Prometheus metrics on /metrics at this address while running, such as :9090 (env SCRAPER_METRICS_ADDR)",
+	)
+	fs.StringVar(
+		&cfg.Progress,
+		"progress",
+		cfg.Progress,
+		"progress display: "+strings.Join(progress.Modes, ", ")+
+			" (auto draws a bar on a terminal and logs a summary line every "+
+			progress.LinesInterval.String()+" otherwise)",
+	)
+	return fs
+}
+
+func scrapeUsage(fs *flag.FlagSet, usage, summary, examples string) func() {
+	return func() {
+		fmt.Fprintf(fs.Output(), "Usage: scraper %s %s\n\n%s\n\n", fs.Name(), usage, summary)
+		fmt.Fprintln(fs.Output(), "Options:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), examples)
+	}
+}
+
+func runScrape(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newScrapeFlagSet("scrape", cfg)
+	rerunFailed := fs.Bool(
+		"rerun-failed",
+		false,
+		"rerun only failed stocks from the previous run (same as the rerun command)",
+	)
+	fs.BoolVar(rerunFailed, "rf", false, "shorthand for -rerun-failed")
+	resume := fs.Bool(
+		"resume",
+		false,
+		"continue the last run from its checkpoint: same stocks, scrapers and date range, skipping finished tasks",
+	)
+	addGroupFlag(fs, cfg)
+	fs.Usage = scrapeUsage(fs, "[options] [stock ... | -]", `Scrape the given stocks, or every stock listed in data/input_stock/, then
+combine the results. Stocks given as arguments (or read from stdin with "-")
+bypass the input directory but are validated the same way.`, `
+Examples:
+  # Default: 5 workers, full date range
+  scraper scrape
+
+  # Custom workers and date range
+  scraper scrape -workers=20 -start=2020-01-01 -end=2024-12-31
+
+  # Store into SQLite as well as CSV
+  scraper scrape -store=both -db=data/scraper.db
+
+  # Parquet raw downloads and combined outputs
+  scraper scrape -format=parquet
+
+  # Newline-delimited JSON for downstream services
+  scraper scrape -format=ndjson
+
+  # Combined XLSX workbooks with Chinese and English headers
+  scraper scrape -combined-format=xlsx -header-lang=both
+
+  # A quick one-off check of two stocks
+  scraper scrape 2330 2317
+
+  # Stock IDs from another command
+  cat ids.txt | scraper scrape -
+
+  # Only the stocks tagged "semis" or "watchlist" in the input list
+  scraper scrape -group=semis,watchlist
+
+  # Settings from a config file, with a flag overriding it
+  scraper scrape -config=scraper.yaml -workers=10
+
+  # Watchlist first, then the stalest data; last run's failures at the end
+  scraper scrape -order=failed,priority,staleness -priority-groups=watchlist
+
+  # Continue a run that was interrupted, even on a later day
+  scraper scrape -resume`)
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+	if *resume {
+		if fs.NArg() > 0 || *rerunFailed {
+			return errors.New("-resume does not take stock arguments or -rerun-failed")
+		}
+		return resumeRun(cfg)
+	}
+	if *rerunFailed {
+		if fs.NArg() > 0 {
+			return errors.New("-rerun-failed does not take stock arguments")
+		}
+		return rerunFailedStocks(cfg)
+	}
+
+	dirs := cfg.Dirs.Resolve()
+	if err := flow.SetupDirectories(dirs.Input, dirs.Download, dirs.FinalOutput, dirs.Failed); err != nil {
+		return err
+	}
+	stocks, err := selectStocks(cfg, fs.Args())
+	if err != nil {
+		return err
+	}
+	return scrapeAndCombine(cfg, stocks, nil)
+}
+
+// resumeRun continues the run recorded in the checkpoint with the stocks,
+// scrapers and date range it was started with, scraping only the tasks that
+// did not finish successfully.
+func resumeRun(cfg *config.Config) error {
+	ledger, err := checkpoint.Open(cfg.CheckpointPath())
+	if err != nil {
+		return err
+	}
+	run := ledger.Run()
+	counts := ledger.Counts()
+	if ledger.Finished() && counts[checkpoint.Done] == len(run.Tasks()) {
+		ledger.Close()
+		slog.Info("The last run finished every task; nothing to resume.", "started_at", run.StartedAt)
+		return nil
+	}
+	slog.Info(
+		"Resuming run.",
+		"started_at", run.StartedAt,
+		"done", counts[checkpoint.Done],
+		"failed", counts[checkpoint.Failed],
+		"pending", counts[checkpoint.Pending],
+	)
+
+	cfg.StartDate, cfg.EndDate = run.StartDate, run.EndDate
+	cfg.Scrapers = run.Scrapers
+	dirs := cfg.Dirs.Resolve()
+	if err := flow.SetupDirectories(dirs.Input, dirs.Download, dirs.FinalOutput, dirs.Failed); err != nil {
+		ledger.Close()
+		return err
+	}
+	return scrapeAndCombine(cfg, run.Stocks, ledger)
+}
+
+// selectStocks returns the stocks named by args (IDs, or "-" to read a stock
+// list from stdin) or, without args, the input stock list, limited to
+// cfg.Groups either way.
+func selectStocks(cfg *config.Config, args []string) ([]string, error) {
+	if len(args) == 0 {
+		return flow.GetStockNumbers(cfg.Dirs.Resolve().Input, cfg.Groups...)
+	}
+	stocks, err := flow.ParseStockArgs(args, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	ids := flow.FilterStocks(stocks, cfg.Groups...)
+	if len(ids) == 0 {
+		return nil, errors.New("no stocks given on the command line (or in the selected groups)")
+	}
+	return ids, nil
+}
+
+// stockArgs returns the stocks named by args, validated like the input list;
+// "-" reads a stock list from stdin.
+func stockArgs(args []string) ([]string, error) {
+	stocks, err := flow.ParseStockArgs(args, os.Stdin)
+	if err != nil {
+		return nil, err
+	}
+	return flow.FilterStocks(stocks), nil
+}
+
+func runRerun(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newScrapeFlagSet("rerun", cfg)
+	fs.Usage = scrapeUsage(fs, "[options]", "Scrape only the stocks recorded as failed by the previous run.", `
+Examples:
+  scraper rerun -workers=3`)
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+	return rerunFailedStocks(cfg)
+}
+
+// rerunFailedStocks scrapes the stocks recorded in the failed list.
+func rerunFailedStocks(cfg *config.Config) error {
+	dirs := cfg.Dirs.Resolve()
+	if err := flow.SetupDirectories(dirs.Input, dirs.Download, dirs.FinalOutput, dirs.Failed); err != nil {
+		return err
+	}
+	stocks, err := storage.LoadFailedStocks(dirs.Failed)
+	if err != nil {
+		return fmt.Errorf("failed to load failed stocks: %w", err)
+	}
+	if len(stocks) == 0 {
+		slog.Info("No recorded failed stocks to rerun. Exiting.")
+		return nil
+	}
+	slog.Info("Rerunning previously failed stocks.", "stocks", len(stocks))
+	return scrapeAndCombine(cfg, stocks, nil)
+}
+
+// scrapeAndCombine runs scrapeWith with a Playwright instance of its own,
+// stopping the scrape on SIGINT or SIGTERM, and serves the metrics meanwhile
+// when cfg.MetricsAddr is set.
+func scrapeAndCombine(cfg *config.Config, stocks []string, ledger *checkpoint.Ledger) error {
+	closeLedger := func() {
+		if ledger != nil {
+			ledger.Close()
+		}
+	}
+	if cfg.MetricsAddr != "" {
+		stopMetrics, err := metrics.Serve(cfg.MetricsAddr)
+		if err != nil {
+			closeLedger()
+			return fmt.Errorf("failed to serve metrics: %w", err)
+		}
+		defer stopMetrics()
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	pw, err := flow.SetupPlaywright()
+	if err != nil {
+		closeLedger()
+		return err
+	}
+	defer pw.Stop()
+	browsers := scraper.NewBrowserPool(pw)
+	defer closeBrowsers(browsers)
+	_, err = scrapeWith(ctx, browsers, cfg, stocks, ledger, false)
+	return err
+}
+
+// scrapeWith scrapes the configured datasets of the stocks into the
+// configured sinks, records the failures, combines the stocks that succeeded
+// and writes the run report. Task states are kept in ledger when resuming, or
+// in a new checkpoint when ledger is nil. Cancelling ctx stops the scrape;
+// the checkpoint is then left open for -resume. A targeted run, scraping a
+// few stocks on request, fetches them even when their data is fresh, since
+// the request may name another date range; it keeps no checkpoint and leaves
+// the failed stocks list and the archives of the full runs alone. The run holds the data
+// directory's lock throughout, so it fails while another run holds it. The
+// report is returned once the scrape has started, even with an error.
+func scrapeWith(
+	ctx context.Context,
+	browsers *scraper.BrowserPool,
+	cfg *config.Config,
+	stocks []string,
+	ledger *checkpoint.Ledger,
+	targeted bool,
+) (*report.Report, error) {
+	if ledger != nil {
+		defer ledger.Close()
+	}
+	runLock, err := lock.Acquire(cfg.LockPath())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := runLock.Release(); err != nil {
+			slog.Warn("Failed to release lock.", logging.KeyError, err)
+		}
+	}()
+
+	slog.Info(
+		"Starting scraper.",
+		"stocks", len(stocks),
+		"scrapers", strings.Join(cfg.Scrapers, ","),
+		"workers", cfg.Workers,
+	)
+	start := time.Now()
+	startDate, endDate := cfg.DateRange()
+	dirs := cfg.Dirs.Resolve()
+
+	failed := previousFailures(cfg, ledger)
+	if ledger == nil && !targeted {
+		ledger, err = checkpoint.Create(cfg.CheckpointPath(), checkpoint.Run{
+			StartedAt: start,
+			StartDate: startDate,
+			EndDate:   endDate,
+			Stocks:    stocks,
+			Scrapers:  cfg.Scrapers,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer ledger.Close()
+	}
+
+	var (
+		sinks  storage.MultiSink
+		sqlite *storage.SQLiteStore
+	)
+	writeFiles := cfg.Output.Store != "sqlite"
+	if writeFiles {
+		fileSink, err := storage.NewFileSink(dirs.Download, cfg.Output.Format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create file sink: %w", err)
+		}
+		sinks = append(sinks, fileSink)
+	}
+	if cfg.Output.Store != "csv" {
+		var err error
+		sqlite, err = storage.OpenSQLite(cfg.DBPath())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite store: %w", err)
+		}
+		defer sqlite.Close()
+		if err := sqlite.BeginRun(startDate, endDate, cfg.Workers, len(stocks)); err != nil {
+			return nil, fmt.Errorf("failed to record run in SQLite store: %w", err)
+		}
+		sinks = append(sinks, sqlite)
+	}
+
+	options := make(map[string]scraper.Options, len(cfg.Scrapers))
+	for _, name := range cfg.Scrapers {
+		o := cfg.Scraper(name)
+		options[name] = scraper.Options{
+			Timeout:   time.Duration(o.Timeout),
+			Freshness: time.Duration(o.Freshness),
+			Retries:   o.Retries,
+		}
+	}
+
+	rep := report.New(start, report.Parameters{
+		Scrapers:       cfg.Scrapers,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		Workers:        cfg.Workers,
+		Store:          cfg.Output.Store,
+		Format:         cfg.Output.Format,
+		CombinedFormat: cfg.Output.Combined(),
+	}, stocks)
+	tracker := progress.NewTracker(len(stocks), cfg.Scrapers)
+	stopProgress := progress.Start(tracker, cfg.Progress, console)
+	downloadStart := time.Now()
+	successStocks, errorStocks := scraper.ScrapeAllStocks(ctx, browsers, stocks, cfg.Scrapers, scraper.RunOptions{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Workers:   cfg.Workers,
+		Sink:      sinks,
+		Options:   options,
+		Record: func(res scraper.Result) {
+			tracker.Record(res.Scraper, res.Outcome)
+			rep.AddTask(res)
+		},
+		Ledger: ledger,
+		Order:  taskOrder(cfg, sinks, failed),
+		Force:  targeted,
+	})
+	interrupted := ctx.Err() != nil
+	stopProgress()
+	if interrupted && targeted {
+		slog.Warn("Interrupted.")
+	} else if interrupted {
+		slog.Warn("Interrupted; continue with \"scraper scrape -resume\".")
+	} else if err := ledger.Finish(); err != nil {
+		slog.Warn("Failed to finish checkpoint.", logging.KeyError, err)
+	}
+	slog.Info("Download process completed.", logging.KeyDuration, time.Since(downloadStart))
+
+	if !targeted {
+		if err := storage.SaveFailedStocks(dirs.Failed, errorStocks); err != nil {
+			return rep, fmt.Errorf("failed to write failed stocks list: %w", err)
+		}
+	}
+
+	if sqlite != nil {
+		if err := sqlite.FinishRun(successStocks, errorStocks); err != nil {
+			slog.Warn("Failed to record run result in SQLite store.", logging.KeyError, err)
+		}
+	}
+
+	if writeFiles {
+		results, err := storage.CombineSuccessfulStocks(
+			successStocks,
+			dirs.Download,
+			dirs.FinalOutput,
+			cfg.Output.Combined(),
+			cfg.Output.HeaderLang,
+		)
+		if err != nil {
+			return rep, fmt.Errorf("error combining successful stocks: %w", err)
+		}
+		rep.AddCombine(results)
+		rep.AddIssues(validateStocks(dirs.Download, successStocks, cfg.Scrapers, storage.DefaultTolerance))
+	} else {
+		slog.Info("File output disabled (-store=sqlite); skipping combine.")
+	}
+
+	slog.Info(
+		"Run summary.",
+		"succeeded", len(successStocks),
+		"failed", len(errorStocks),
+		"failed_stocks", strings.Join(errorStocks, ","),
+		logging.KeyDuration, time.Since(start),
+	)
+
+	rep.Finish(time.Now())
+	reportPath, err := writeReport(rep, dirs.Report, cfg.Output.ReportHTML)
+	event := notify.NewEvent(rep, reportPath)
+	event.Interrupted = interrupted
+	notifyRun(cfg, event)
+
+	if cfg.Archive.AfterScrape && writeFiles && !interrupted && !targeted {
+		if run, err := json.Marshal(rep); err != nil {
+			slog.Warn("Failed to archive data.", logging.KeyError, err)
+		} else if err := archiveData(cfg, time.Now().Format("2006-01-02"), run); err != nil {
+			slog.Warn("Failed to archive data.", logging.KeyError, err)
+		}
+	}
+	return rep, err
+}
+
+// closeBrowsers closes the browsers of a pool; a browser that fails to close
+// is only logged, as the scrape is over.
+func closeBrowsers(browsers *scraper.BrowserPool) {
+	if err := browsers.Close(); err != nil {
+		slog.Warn("Failed to close browsers.", logging.KeyError, err)
+	}
+}
+
+// notifyRun sends event to the configured webhooks. Failing to notify is
+// logged; the run itself succeeded.
+func notifyRun(cfg *config.Config, event notify.Event) {
+	if len(cfg.Notify.Webhooks) == 0 {
+		return
+	}
+	hooks := make([]notify.Webhook, len(cfg.Notify.Webhooks))
+	for i, h := range cfg.Notify.Webhooks {
+		hooks[i] = notify.Webhook{URL: h.URL, Headers: h.Headers, Format: h.Format, To: h.To, Template: h.Template}
+	}
+	n, err := notify.New(notify.Options{
+		Always:      cfg.Notify.Always,
+		FailureRate: cfg.Notify.FailureRate,
+		Webhooks:    hooks,
+	})
+	if err != nil {
+		slog.Warn("Failed to set up notifications.", logging.KeyError, err)
+		return
+	}
+	sent, err := n.Notify(context.Background(), event)
+	if err != nil {
+		slog.Warn("Failed to send notification.", logging.KeyError, err)
+	} else if sent {
+		slog.Info("Sent run notification.", "reason", event.Reason, "webhooks", len(hooks))
+	}
+}
+
+// previousFailures returns the tasks that failed in the checkpointed run:
+// ledger when resuming, otherwise the checkpoint about to be replaced.
+func previousFailures(cfg *config.Config, ledger *checkpoint.Ledger) map[scraper.Task]bool {
+	if ledger == nil {
+		prev, err := checkpoint.Open(cfg.CheckpointPath())
+		if err != nil {
+			return nil
+		}
+		defer prev.Close()
+		ledger = prev
+	}
+	failed := make(map[scraper.Task]bool)
+	for _, t := range ledger.Run().Tasks() {
+		if ledger.State(t) == checkpoint.Failed {
+			failed[scraper.Task{Stock: t.Stock, Scraper: t.Scraper}] = true
+		}
+	}
+	return failed
+}
+
+// taskOrder returns the queue ordering selected by cfg.Order.
+func taskOrder(cfg *config.Config, sink storage.Sink, failed map[scraper.Task]bool) func([]scraper.Task) {
+	var ranks func(stock string) int
+	if slices.Contains(cfg.Order, "priority") {
+		ranks = priorityRanks(cfg)
+	}
+	return func(tasks []scraper.Task) {
+		var policies []scraper.Policy
+		for _, name := range cfg.Order {
+			switch name {
+			case "priority":
+				policies = append(policies, scraper.ByPriority(ranks))
+			case "staleness":
+				policies = append(policies, scraper.ByStaleness(lastSavedTimes(sink, tasks)))
+			case "failed":
+				policies = append(policies, scraper.FailedLast(func(t scraper.Task) bool { return failed[t] }))
+			case "random":
+				scraper.Shuffle(tasks, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+			}
+		}
+		scraper.Order(tasks, policies...)
+	}
+}
+
+// lastSavedTimes looks up when the data of each task was last saved, once
+// per task, so that sorting by staleness does no I/O in the comparator.
+func lastSavedTimes(sink storage.Sink, tasks []scraper.Task) func(scraper.Task) (time.Time, bool) {
+	saved := make(map[scraper.Task]time.Time, len(tasks))
+	for _, t := range tasks {
+		if at, ok := sink.LastSaved(t.Stock, t.Scraper); ok {
+			saved[t] = at
+		}
+	}
+	return func(t scraper.Task) (time.Time, bool) {
+		at, ok := saved[t]
+		return at, ok
+	}
+}
+
+// priorityRanks ranks each stock of the input list by the first of
+// cfg.PriorityGroups it is tagged with; other stocks rank last.
+func priorityRanks(cfg *config.Config) func(stock string) int {
+	ranks := make(map[string]int)
+	stocks, err := flow.LoadStockList(cfg.Dirs.Resolve().Input)
+	if err != nil {
+		slog.Warn("Cannot read input groups; -order=priority has no effect.", logging.KeyError, err)
+	}
+	for _, s := range stocks {
+		for i, g := range cfg.PriorityGroups {
+			if s.InGroup(g) {
+				ranks[s.ID] = i
+				break
+			}
+		}
+	}
+	return func(stock string) int {
+		if r, ok := ranks[stock]; ok {
+			return r
+		}
+		return len(cfg.PriorityGroups)
+	}
+}
+
+// writeReport writes the run report to dir as JSON and, when html is set,
+// as an HTML page. It returns the path of the JSON report.
+func writeReport(rep *report.Report, dir string, html bool) (string, error) {
+	path, err := rep.WriteJSON(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to write run report: %w", err)
+	}
+	slog.Info("Wrote run report.", "file", path)
+	if html {
+		htmlPath, err := rep.WriteHTML(dir)
+		if err != nil {
+			return path, fmt.Errorf("failed to write HTML run report: %w", err)
+		}
+		slog.Info("Wrote run report.", "file", htmlPath)
+	}
+	return path, nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+	// Schedules name IANA zones, which slim container images lack.
+	_ "time/tzdata"
+
+	"github.com/ysonC/multi-stocks-download/internal/api"
+	"github.com/ysonC/multi-stocks-download/internal/config"
+	"github.com/ysonC/multi-stocks-download/internal/flow"
+	"github.com/ysonC/multi-stocks-download/internal/metrics"
+	"github.com/ysonC/multi-stocks-download/internal/report"
+	"github.com/ysonC/multi-stocks-download/internal/schedule"
+	"github.com/ysonC/multi-stocks-download/internal/scraper"
+)
+
+// listRuns is how many upcoming runs of each job -list prints.
+const listRuns = 3
+
+// shutdownTimeout bounds how long the HTTP API waits for open requests
+// when the daemon stops.
+const shutdownTimeout = 10 * time.Second
+
+func runServe(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newScrapeFlagSet("serve", cfg)
+	list := fs.Bool("list", false, "print the next run times of each job and exit")
+	fs.StringVar(
+		&cfg.Daemon.APIAddr,
+		"addr",
+		cfg.Daemon.APIAddr,
+		"listen address of the HTTP API, such as :8080; empty disables it (env SCRAPER_API_ADDR)",
+	)
+	fs.Usage = scrapeUsage(fs, "[options]", `Run the jobs of the config file's daemon section at their scheduled times,
+and serve the HTTP API when -addr is set, until interrupted. Each job scrapes
+and combines like the scrape command, with its own scrapers and groups; the
+other settings are shared. Scheduled jobs and scrapes requested over the API
+run one at a time on one Playwright instance, and a scrape that would overlap
+another run of the same data directory fails instead.`, `
+Examples:
+  # Run the configured jobs
+  scraper serve -config=scraper.yaml
+
+  # Check when each job runs next
+  scraper serve -config=scraper.yaml -list
+
+  # Serve the HTTP API as well
+  SCRAPER_API_TOKEN=s3cret scraper serve -config=scraper.yaml -addr=:8080`)
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return errors.New("serve does not take stock arguments; use the jobs' groups")
+	}
+	if len(cfg.Daemon.Jobs) == 0 && cfg.Daemon.APIAddr == "" {
+		return errors.New("nothing to serve; add jobs to the daemon section of the config file or set -addr")
+	}
+	loc, err := cfg.Daemon.Location()
+	if err != nil {
+		return err
+	}
+
+	// Scrapes run one at a time, whether scheduled or requested, and share
+	// the workers' browsers, which stay open between runs.
+	var (
+		browsers *scraper.BrowserPool
+		runMu    sync.Mutex
+	)
+	// API requests are targeted runs; see scrapeWith.
+	scrape := func(ctx context.Context, cfg *config.Config, stocks []string, targeted bool) (*report.Report, error) {
+		runMu.Lock()
+		defer runMu.Unlock()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return scrapeWith(ctx, browsers, cfg, stocks, nil, targeted)
+	}
+
+	jobs := make([]schedule.Job, len(cfg.Daemon.Jobs))
+	for i, job := range cfg.Daemon.Jobs {
+		jobCfg := *cfg
+		if len(job.Scrapers) > 0 {
+			jobCfg.Scrapers = job.Scrapers
+		}
+		if len(job.Groups) > 0 {
+			jobCfg.Groups = job.Groups
+		}
+		jobs[i] = schedule.Job{
+			Name: job.Name,
+			Run: func(ctx context.Context) error {
+				stocks, err := selectStocks(&jobCfg, nil)
+				if err != nil {
+					return err
+				}
+				_, err = scrape(ctx, &jobCfg, stocks, false)
+				return err
+			},
+		}
+		for _, expr := range job.Schedules {
+			s, err := schedule.Parse(expr)
+			if err != nil {
+				return err
+			}
+			jobs[i].Schedules = append(jobs[i].Schedules, s)
+		}
+	}
+
+	if *list {
+		return printJobs(cfg, jobs, time.Now().In(loc))
+	}
+
+	dirs := cfg.Dirs.Resolve()
+	if err := flow.SetupDirectories(dirs.Input, dirs.Download, dirs.FinalOutput, dirs.Failed); err != nil {
+		return err
+	}
+	var listener net.Listener
+	if cfg.Daemon.APIAddr != "" {
+		if listener, err = net.Listen("tcp", cfg.Daemon.APIAddr); err != nil {
+			return err
+		}
+	}
+	if cfg.MetricsAddr != "" {
+		stopMetrics, err := metrics.Serve(cfg.MetricsAddr)
+		if err != nil {
+			if listener != nil {
+				listener.Close()
+			}
+			return fmt.Errorf("failed to serve metrics: %w", err)
+		}
+		defer stopMetrics()
+	}
+	pw, err := flow.SetupPlaywright()
+	if err != nil {
+		if listener != nil {
+			listener.Close()
+		}
+		return err
+	}
+	defer pw.Stop()
+	browsers = scraper.NewBrowserPool(pw)
+	defer closeBrowsers(browsers)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// Stops the scheduler and the API's runner when the API fails.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if len(jobs) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			schedule.Run(ctx, loc, jobs)
+		}()
+	}
+	if listener == nil {
+		<-ctx.Done()
+		return nil
+	}
+	return serveAPI(ctx, &wg, listener, cfg, func(ctx context.Context, req api.Request) (*report.Report, error) {
+		reqCfg := *cfg
+		reqCfg.Scrapers = req.Scrapers
+		if req.StartDate != "" {
+			reqCfg.StartDate, reqCfg.EndDate = req.StartDate, req.EndDate
+		}
+		return scrape(ctx, &reqCfg, req.Stocks, true)
+	})
+}
+
+// serveAPI serves the HTTP API on listener until ctx is cancelled, carrying
+// out requested scrapes with run in a goroutine tracked by wg.
+func serveAPI(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	listener net.Listener,
+	cfg *config.Config,
+	run api.Runner,
+) error {
+	dirs := cfg.Dirs.Resolve()
+	srv := api.New(api.Options{
+		DownloadDir: dirs.Download,
+		InputDir:    dirs.Input,
+		Scrapers:    cfg.Scrapers,
+		Freshness: func(dataset string) time.Duration {
+			return time.Duration(cfg.Scraper(dataset).Freshness)
+		},
+		HeaderLang: cfg.Output.HeaderLang,
+		Token:      cfg.Daemon.APIToken,
+		Run:        run,
+	})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		srv.Run(ctx)
+	}()
+
+	httpServer := &http.Server{Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() { errc <- httpServer.Serve(listener) }()
+	slog.Info("Serving HTTP API.", "addr", listener.Addr().String())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return httpServer.Shutdown(shutdownCtx)
+}
+
+// printJobs lists the settings of each job and its next listRuns run times
+// after now.
+func printJobs(cfg *config.Config, jobs []schedule.Job, now time.Time) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSCHEDULES\tSCRAPERS\tGROUPS\tNEXT RUNS")
+	for i, job := range jobs {
+		configured := cfg.Daemon.Jobs[i]
+		scrapers, groups := configured.Scrapers, configured.Groups
+		if len(scrapers) == 0 {
+			scrapers = cfg.Scrapers
+		}
+		if len(groups) == 0 {
+			groups = cfg.Groups
+		}
+		var next []string
+		for t := now; len(next) < listRuns; {
+			if t = job.Next(t); t.IsZero() {
+				break
+			}
+			next = append(next, t.Format("2006-01-02 15:04 MST"))
+		}
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\n",
+			job.Name,
+			strings.Join(configured.Schedules, " | "),
+			strings.Join(scrapers, ","),
+			orAll(groups),
+			strings.Join(next, ", "),
+		)
+	}
+	return w.Flush()
+}
+
+// orAll returns the groups joined by commas, or "(all)" when there are none.
+func orAll(list []string) string {
+	if len(list) == 0 {
+		return "(all)"
+	}
+	return strings.Join(list, ",")
+}
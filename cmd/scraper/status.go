@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+func runStatus(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("status", cfg)
+	staleOnly := fs.Bool("stale", false, "only list stocks with stale or missing datasets")
+	addGroupFlag(fs, cfg)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper status [options] [stock ... | -]")
+		fmt.Fprintln(fs.Output(), `
+Show how old each dataset of the given stocks (default: every stock in
+data/input_stock/) is, without launching a browser. A dataset is fresh when
+scrape would skip it: downloaded today, or within the scraper's configured
+freshness.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+
+	dirs := cfg.Dirs.Resolve()
+	stocks, err := selectStocks(cfg, fs.Args())
+	if err != nil {
+		return err
+	}
+	datasets := cfg.Scrapers
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "STOCK")
+	for _, name := range datasets {
+		fmt.Fprintf(w, "\t%s", name)
+	}
+	fmt.Fprintln(w)
+
+	var fresh, stale, missing int
+	for _, stock := range stocks {
+		cells := make([]string, len(datasets))
+		allFresh := true
+		for i, name := range datasets {
+			modTime, err := storage.DatasetModTime(dirs.Download, stock, name)
+			switch {
+			case err != nil:
+				cells[i] = "missing"
+				missing++
+				allFresh = false
+			case storage.IsFresh(modTime, time.Duration(cfg.Scraper(name).Freshness)):
+				cells[i] = "fresh"
+				fresh++
+			default:
+				cells[i] = formatAge(modTime)
+				stale++
+				allFresh = false
+			}
+		}
+		if *staleOnly && allFresh {
+			continue
+		}
+		fmt.Fprint(w, stock)
+		for _, cell := range cells {
+			fmt.Fprintf(w, "\t%s", cell)
+		}
+		fmt.Fprintln(w)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf(
+		"\n%d stock(s): %d fresh, %d stale, %d missing dataset(s).\n",
+		len(stocks),
+		fresh,
+		stale,
+		missing,
+	)
+	return nil
+}
+
+// formatAge renders how many days ago t was, e.g. "3d old".
+func formatAge(t time.Time) string {
+	days := int(time.Since(t).Hours() / 24)
+	if days < 1 {
+		days = 1
+	}
+	return fmt.Sprintf("%dd old", days)
+}
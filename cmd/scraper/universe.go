@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/config"
+	"github.com/ysonC/multi-stocks-download/internal/flow"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/universe"
+)
+
+// universeListFile is the stock list written into the input directory by
+// -write-input.
+const universeListFile = "universe.txt"
+
+func runUniverse(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("universe", cfg)
+	marketsFlag := fs.String(
+		"markets",
+		"listed,otc",
+		"comma-separated markets to fetch: "+strings.Join(universe.MarketNames(), ", "),
+	)
+	categoriesFlag := fs.String(
+		"categories",
+		"stock",
+		"comma-separated security categories to keep: "+strings.Join(
+			slices.Sorted(maps.Keys(universe.Categories)),
+			", ",
+		),
+	)
+	outFlag := fs.String("out", "", "universe file (default <data-dir>/"+config.UniverseFile+")")
+	writeInput := fs.Bool(
+		"write-input",
+		false,
+		"also write the universe as <input-dir>/"+universeListFile+", grouped by market and industry",
+	)
+	dryRun := fs.Bool("dry-run", false, "fetch and report the differences without writing anything")
+	timeout := fs.Duration("timeout", time.Minute, "timeout for fetching each market's list")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper universe [options]")
+		fmt.Fprintln(fs.Output(), `
+Fetch the listed-company universe from the TWSE ISIN pages into a CSV with
+each security's ID, name, market, industry, category and listing date. The
+result is compared with the previous universe file (new listings, delistings
+and changes) and with the current input stock list.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+		fmt.Fprintln(fs.Output(), `
+Examples:
+  # Refresh data/universe.csv and see what changed
+  scraper universe
+
+  # Replace the input list with every listed and OTC stock, then scrape semiconductors
+  scraper universe -write-input
+  scraper scrape -group=半導體業`)
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+
+	markets := config.SplitList(*marketsFlag)
+	for _, m := range markets {
+		if !slices.Contains(universe.MarketNames(), m) {
+			return fmt.Errorf(
+				"unknown market %q, must be one of %s",
+				m,
+				strings.Join(universe.MarketNames(), ", "),
+			)
+		}
+	}
+	categories := config.SplitList(*categoriesFlag)
+	dirs := cfg.Dirs.Resolve()
+	out := *outFlag
+	if out == "" {
+		out = filepath.Join(dirs.Data, config.UniverseFile)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout*time.Duration(len(markets)))
+	defer cancel()
+	fetchedAt := time.Now()
+	companies, err := universe.FetchAll(ctx, http.DefaultClient, universe.ISINURL, markets, categories)
+	if err != nil {
+		return err
+	}
+	slog.Info("Fetched universe.", "securities", len(companies), "markets", strings.Join(markets, ","))
+
+	prev, err := universe.ReadCSV(out)
+	switch {
+	case err == nil:
+		printUniverseDiff(os.Stdout, universe.Compare(prev, companies))
+	case os.IsNotExist(err):
+		slog.Info("No previous universe.", "file", out)
+	default:
+		return err
+	}
+	compareWithInput(os.Stdout, dirs.Input, companies)
+
+	if *dryRun {
+		return nil
+	}
+	if err := universe.WriteCSV(out, companies); err != nil {
+		return fmt.Errorf("failed to write universe: %w", err)
+	}
+	slog.Info("Wrote universe.", "file", out)
+	if *writeInput {
+		path := filepath.Join(dirs.Input, universeListFile)
+		if err := universe.WriteStockList(path, companies, fetchedAt); err != nil {
+			return fmt.Errorf("failed to write stock list: %w", err)
+		}
+		slog.Info(
+			"Wrote stock list; remove other lists from the input directory to scrape exactly the universe.",
+			"file", path,
+		)
+	}
+	return nil
+}
+
+// printUniverseDiff reports the companies added, removed and changed since
+// the previous universe.
+func printUniverseDiff(w io.Writer, d universe.Diff) {
+	if d.Empty() {
+		fmt.Fprintln(w, "Universe unchanged.")
+		return
+	}
+	for _, c := range d.Added {
+		fmt.Fprintf(w, "+ %s %s (%s, listed %s)\n", c.ID, c.Name, c.Market, c.ListedOn)
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(w, "- %s %s (%s)\n", c.ID, c.Name, c.Market)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "~ %s %s: %s\n", c.New.ID, c.New.Name, strings.Join(c.Describe(), ", "))
+	}
+	fmt.Fprintf(
+		w,
+		"%d added, %d removed, %d changed.\n",
+		len(d.Added),
+		len(d.Removed),
+		len(d.Changed),
+	)
+}
+
+// compareWithInput reports stocks in the input list that are missing from the
+// universe (delisted, or in a market or category that was not fetched) and
+// how many universe companies the input list does not cover.
+func compareWithInput(w io.Writer, inputDir string, companies []universe.Company) {
+	stocks, err := flow.LoadStockList(inputDir)
+	if err != nil {
+		slog.Warn("Skipping comparison with the input list.", logging.KeyError, err)
+		return
+	}
+
+	inUniverse := make(map[string]bool, len(companies))
+	for _, c := range companies {
+		inUniverse[c.ID] = true
+	}
+	inInput := make(map[string]bool, len(stocks))
+	var notInUniverse []string
+	for _, s := range stocks {
+		inInput[s.ID] = true
+		if !inUniverse[s.ID] {
+			notInUniverse = append(notInUniverse, s.ID)
+		}
+	}
+	var notInInput []string
+	for _, c := range companies {
+		if !inInput[c.ID] {
+			notInInput = append(notInInput, c.ID)
+		}
+	}
+
+	fmt.Fprintf(w, "Input list: %d stock(s), %d not in the universe", len(stocks), len(notInUniverse))
+	if len(notInUniverse) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(notInUniverse, " "))
+	}
+	fmt.Fprintf(w, "; %d universe stock(s) not in the input list", len(notInInput))
+	if len(notInInput) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(notInInput, " "))
+	}
+	fmt.Fprintln(w, ".")
+}
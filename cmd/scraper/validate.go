@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+func runValidate(args []string) error {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return err
+	}
+	fs := newFlagSet("validate", cfg)
+	datasetsFlag := fs.String(
+		"datasets",
+		strings.Join(storage.DatasetNames(), ","),
+		"comma-separated datasets to check",
+	)
+	strict := fs.Bool("strict", false, "exit non-zero on warnings too")
+	tolerance := fs.Float64(
+		"tolerance",
+		storage.DefaultTolerance,
+		"relative difference allowed between prices of the same period in two datasets",
+	)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: scraper validate [options] [stock ... | -]")
+		fmt.Fprintln(fs.Output(), `
+Check the downloaded data of the given stocks (default: every stock in
+data/downloaded_stock/). Every dataset must exist and hold records; the
+records are checked for unrecognized, duplicate and out-of-order periods,
+rows without values, unparsable numbers and negative prices (errors), and
+for gaps in the week/month/quarter calendar and closing prices jumping more
+than tenfold between periods (warnings). Prices that overlap between
+datasets are cross-checked as well (warnings): the weekly closes of per and
+stockdata, and the monthly prices of monthlyrevenue against the weekly
+prices of stockdata. Exits non-zero when any error is found.`)
+		fmt.Fprintln(fs.Output(), "\nOptions:")
+		fs.PrintDefaults()
+	}
+	if err := parseWithConfig(fs, cfg, args); err != nil {
+		return err
+	}
+	if *tolerance < 0 {
+		return fmt.Errorf("invalid -tolerance %g: must be >= 0", *tolerance)
+	}
+
+	downloadDir := cfg.Dirs.Resolve().Download
+	stocks, err := stockArgs(fs.Args())
+	if err != nil {
+		return err
+	}
+	if len(stocks) == 0 {
+		stocks, err = storage.ListStocks(downloadDir)
+		if err != nil {
+			return fmt.Errorf("failed to list downloaded stocks: %w", err)
+		}
+	}
+
+	issues := validateStocks(downloadDir, stocks, strings.Split(*datasetsFlag, ","), *tolerance)
+	errs := 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Severity == storage.SeverityError {
+			errs++
+		}
+	}
+	if errs > 0 || (*strict && len(issues) > 0) {
+		return fmt.Errorf("%d error(s) and %d warning(s) found", errs, len(issues)-errs)
+	}
+	return nil
+}
+
+// validateStocks checks the given datasets of every stock in downloadDir,
+// cross-checks them against each other and logs how many issues were found.
+func validateStocks(downloadDir string, stocks, datasets []string, tolerance float64) []storage.Issue {
+	var issues []storage.Issue
+	for _, stock := range stocks {
+		issues = append(issues, storage.ValidateStock(downloadDir, stock, datasets...)...)
+		issues = append(issues, storage.CrossCheckStock(downloadDir, stock, tolerance, datasets...)...)
+	}
+	errs := 0
+	for _, issue := range issues {
+		if issue.Severity == storage.SeverityError {
+			errs++
+		}
+	}
+	slog.Info("Validation finished.", "stocks", len(stocks), "errors", errs, "warnings", len(issues)-errs)
+	return issues
+}
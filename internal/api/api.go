@@ -0,0 +1,386 @@
+// Package api serves the scraper over HTTP: it queues scrape requests,
+// reports their progress, and serves the freshness and contents of the
+// downloaded data.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/flow"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+// RawFormats lists the formats a single dataset can be downloaded in.
+var RawFormats = []string{"csv", "json", "ndjson", "xlsx"}
+
+// contentTypes maps download formats to their media types.
+var contentTypes = map[string]string{
+	"csv":     "text/csv; charset=utf-8",
+	"json":    "application/json",
+	"ndjson":  "application/x-ndjson",
+	"xlsx":    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"parquet": "application/vnd.apache.parquet",
+}
+
+// maxRequestBody bounds the size of a scrape request.
+const maxRequestBody = 1 << 20
+
+// Options configures a Server.
+type Options struct {
+	// DownloadDir holds the raw per-stock downloads; InputDir the input
+	// stock list.
+	DownloadDir string
+	InputDir    string
+	// Scrapers are the datasets scraped when a request names none, and the
+	// datasets listed for each stock.
+	Scrapers []string
+	// Freshness returns how old a dataset may be and still count as fresh.
+	Freshness func(dataset string) time.Duration
+	// HeaderLang is the default header language of combined downloads.
+	HeaderLang string
+	// Token, when set, must be sent as "Authorization: Bearer <token>" with
+	// every request but GET /api/health.
+	Token string
+	// Run carries out scrape requests.
+	Run Runner
+}
+
+// Server is the HTTP API. Its Handler serves requests; Run carries out the
+// queued scrapes.
+type Server struct {
+	opts  Options
+	queue chan *Job
+
+	mu     sync.Mutex
+	jobs   []*Job // oldest first
+	nextID int
+}
+
+// New returns a Server with an empty queue.
+func New(opts Options) *Server {
+	return &Server{opts: opts, queue: make(chan *Job, QueueSize)}
+}
+
+// Handler returns the handler of the API's routes:
+//
+//	GET  /api/health
+//	POST /api/scrapes                            queue a scrape (see Request)
+//	GET  /api/scrapes                            recent scrapes, newest first
+//	GET  /api/scrapes/{id}                       one scrape and, once finished, its report
+//	GET  /api/stocks[?group=a,b]                 input stocks and dataset freshness
+//	GET  /api/stocks/{stock}                     one stock's dataset freshness
+//	GET  /api/stocks/{stock}/data/{dataset}      raw dataset; ?format= one of RawFormats
+//	GET  /api/stocks/{stock}/combined            combined datasets; ?format= and ?lang=
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("POST /api/scrapes", s.createScrape)
+	mux.HandleFunc("GET /api/scrapes", s.listScrapes)
+	mux.HandleFunc("GET /api/scrapes/{id}", s.getScrape)
+	mux.HandleFunc("GET /api/stocks", s.listStocks)
+	mux.HandleFunc("GET /api/stocks/{stock}", s.getStock)
+	mux.HandleFunc("GET /api/stocks/{stock}/data/{dataset}", s.getDataset)
+	mux.HandleFunc("GET /api/stocks/{stock}/combined", s.getCombined)
+	return s.authorize(mux)
+}
+
+// authorize rejects requests without the configured bearer token.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	if s.opts.Token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.opts.Token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if r.URL.Path != "/api/health" && subtle.ConstantTimeCompare(got, want) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) createScrape(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if err := req.normalize(s.opts.Scrapers); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	job, err := s.enqueue(req)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	slog.Info("Queued requested scrape.", "job", job.ID, "stocks", strings.Join(req.Stocks, ","))
+	w.Header().Set("Location", "/api/scrapes/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (s *Server) listScrapes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.allJobs())
+}
+
+func (s *Server) getScrape(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no scrape %q", r.PathValue("id")))
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// Stock is a stock and the freshness of its datasets.
+type Stock struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name,omitempty"`
+	Groups   []string  `json:"groups,omitempty"`
+	Datasets []Dataset `json:"datasets"`
+}
+
+// Dataset is the freshness of one dataset of a stock.
+type Dataset struct {
+	Name string `json:"name"`
+	// UpdatedAt is unset when the dataset was never downloaded.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// Fresh is whether a scrape would skip the dataset.
+	Fresh bool `json:"fresh"`
+}
+
+// stockStatus returns the freshness of each of the configured datasets of
+// stock.
+func (s *Server) stockStatus(stock flow.Stock) Stock {
+	st := Stock{ID: stock.ID, Name: stock.Name, Groups: stock.Groups}
+	for _, name := range s.opts.Scrapers {
+		d := Dataset{Name: name}
+		if modTime, err := storage.DatasetModTime(s.opts.DownloadDir, stock.ID, name); err == nil {
+			d.UpdatedAt = &modTime
+			d.Fresh = storage.IsFresh(modTime, s.opts.Freshness(name))
+		}
+		st.Datasets = append(st.Datasets, d)
+	}
+	return st
+}
+
+func (s *Server) listStocks(w http.ResponseWriter, r *http.Request) {
+	stocks, err := flow.LoadStockList(s.opts.InputDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var groups []string
+	if g := r.URL.Query().Get("group"); g != "" {
+		groups = strings.Split(g, ",")
+	}
+	list := []Stock{}
+	for _, stock := range stocks {
+		if len(groups) == 0 || stock.InGroup(groups...) {
+			list = append(list, s.stockStatus(stock))
+		}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// pathStock returns the stock named in the request path, with its name and
+// groups when it is in the input list. It writes an error response and
+// returns false when the ID is invalid.
+func (s *Server) pathStock(w http.ResponseWriter, r *http.Request) (flow.Stock, bool) {
+	id := strings.ToUpper(r.PathValue("stock"))
+	if !flow.ValidStockID(id) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid stock id %q", id))
+		return flow.Stock{}, false
+	}
+	stocks, _ := flow.LoadStockList(s.opts.InputDir)
+	if i := slices.IndexFunc(stocks, func(st flow.Stock) bool { return st.ID == id }); i >= 0 {
+		return stocks[i], true
+	}
+	return flow.Stock{ID: id}, true
+}
+
+func (s *Server) getStock(w http.ResponseWriter, r *http.Request) {
+	stock, ok := s.pathStock(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.stockStatus(stock))
+}
+
+func (s *Server) getDataset(w http.ResponseWriter, r *http.Request) {
+	stock, ok := s.pathStock(w, r)
+	if !ok {
+		return
+	}
+	d, err := storage.LookupDataset(r.PathValue("dataset"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	format := formatParam(r, "csv")
+	if !slices.Contains(RawFormats, format) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(
+			"invalid format %q, must be one of %s", format, strings.Join(RawFormats, ", "),
+		))
+		return
+	}
+	data, err := storage.ReadDataset(s.opts.DownloadDir, stock.ID, d.Name)
+	if err != nil {
+		writeReadError(w, err)
+		return
+	}
+
+	name := stock.ID + "-" + d.Name + "." + format
+	switch format {
+	case "csv":
+		setDownload(w, format, name)
+		cw := csv.NewWriter(w)
+		if err := cw.WriteAll(data); err != nil {
+			slog.Warn("Failed to write response.", logging.KeyStock, stock.ID, logging.KeyError, err)
+		}
+	case "json", "ndjson":
+		meta := storage.RecordMeta{StockID: stock.ID, Dataset: d.Name, Source: d.Source}
+		meta.ScrapedAt, _ = storage.DatasetModTime(s.opts.DownloadDir, stock.ID, d.Name)
+		setDownload(w, format, name)
+		jw := storage.NewJSONRecordWriter(w, format == "ndjson")
+		for _, record := range d.Records(data) {
+			if err := jw.Write(meta, d, record); err != nil {
+				return
+			}
+		}
+		jw.Close()
+	case "xlsx":
+		s.serveGenerated(w, r, name, func(path string) error {
+			return storage.WriteDatasetXLSX(path, d, data)
+		})
+	}
+}
+
+func (s *Server) getCombined(w http.ResponseWriter, r *http.Request) {
+	stock, ok := s.pathStock(w, r)
+	if !ok {
+		return
+	}
+	format := formatParam(r, "csv")
+	if !slices.Contains(storage.CombinedFormats, format) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(
+			"invalid format %q, must be one of %s", format, strings.Join(storage.CombinedFormats, ", "),
+		))
+		return
+	}
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = s.opts.HeaderLang
+	}
+	if !slices.Contains(storage.HeaderLangs, lang) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf(
+			"invalid lang %q, must be one of %s", lang, strings.Join(storage.HeaderLangs, ", "),
+		))
+		return
+	}
+	// Combining needs every dataset.
+	for _, name := range storage.DatasetNames() {
+		if _, err := storage.DatasetModTime(s.opts.DownloadDir, stock.ID, name); err != nil {
+			writeReadError(w, err)
+			return
+		}
+	}
+
+	s.serveGenerated(w, r, stock.ID+"."+format, func(path string) error {
+		results, err := storage.CombineSuccessfulStocks(
+			[]string{stock.ID}, s.opts.DownloadDir, filepath.Dir(path), format, lang,
+		)
+		if err != nil {
+			return err
+		}
+		return results[0].Err
+	})
+}
+
+// serveGenerated serves the file that generate writes to a temporary path
+// ending in name.
+func (s *Server) serveGenerated(w http.ResponseWriter, r *http.Request, name string, generate func(path string) error) {
+	dir, err := os.MkdirTemp("", "scraper-api-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, filepath.Base(name))
+	if err := generate(path); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	setDownload(w, filepath.Ext(name)[1:], name)
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// formatParam returns the format query parameter, or def when it is absent.
+func formatParam(r *http.Request, def string) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return strings.ToLower(f)
+	}
+	return def
+}
+
+// setDownload sets the headers of a file download.
+func setDownload(w http.ResponseWriter, format, name string) {
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+}
+
+// writeReadError reports a failure to read stored data: 404 when it was
+// never downloaded.
+func writeReadError(w http.ResponseWriter, err error) {
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes v as an indented JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Warn("Failed to write response.", logging.KeyError, err)
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/report"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+// newTestServer returns a server over a download directory holding the per
+// dataset of 2330, and an input list of 2330 and 2317.
+func newTestServer(t *testing.T, opts Options) (*Server, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	opts.DownloadDir = filepath.Join(dir, "downloaded_stock")
+	opts.InputDir = filepath.Join(dir, "input_stock")
+	if opts.Scrapers == nil {
+		opts.Scrapers = []string{"per", "cashflow"}
+	}
+	opts.Freshness = func(string) time.Duration { return 0 }
+	opts.HeaderLang = "zh"
+
+	if err := os.MkdirAll(opts.InputDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	list := "2330,TSMC,semis;watchlist\n2317,Hon Hai,electronics\n"
+	if err := os.WriteFile(filepath.Join(opts.InputDir, "stocks.txt"), []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := [][]string{
+		{"交易週別", "收盤價", "漲跌價", "漲跌幅", "河流圖 EPS(元)", "目前 PER (倍)"},
+		{"24W02", "1,090", "+10", "+1.72%", "-", "18.3"},
+	}
+	if err := storage.NewCSVSink(opts.DownloadDir).Save("2330", "per", data); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(opts)
+	ts := httptest.NewServer(s.Handler())
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s: %v", url, err)
+	}
+	return resp, string(body)
+}
+
+func TestCreateScrapeValidation(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"no stocks", `{"stocks": []}`, "at least one stock"},
+		{"bad stock", `{"stocks": ["2330", "../x"]}`, "invalid stock id"},
+		{"unknown scraper", `{"stocks": ["2330"], "scrapers": ["dividends"]}`, "unknown scraper"},
+		{"half range", `{"stocks": ["2330"], "start_date": "2024-01-01"}`, "set together"},
+		{"reversed range", `{"stocks": ["2330"], "start_date": "2024-01-01", "end_date": "2023-01-01"}`, "is before"},
+		{"unknown field", `{"stocks": ["2330"], "workers": 3}`, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(ts.URL+"/api/scrapes", "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), tt.wantErr) {
+				t.Fatalf("got %d %s, want 400 containing %q", resp.StatusCode, body, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScrapeLifecycle(t *testing.T) {
+	requests := make(chan Request, 1)
+	s, ts := newTestServer(t, Options{
+		Run: func(ctx context.Context, req Request) (*report.Report, error) {
+			requests <- req
+			return report.New(time.Now(), report.Parameters{Scrapers: req.Scrapers}, req.Stocks), nil
+		},
+	})
+
+	resp, err := http.Post(ts.URL+"/api/scrapes", "application/json", strings.NewReader(`{"stocks": ["2330", "2330", "00679b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job Job
+	if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || job.Status != StatusQueued {
+		t.Fatalf("POST returned %d with status %q, want 202 queued", resp.StatusCode, job.Status)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/api/scrapes/"+job.ID {
+		t.Fatalf("Location = %q", loc)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	want := Request{Stocks: []string{"2330", "00679B"}, Scrapers: []string{"per", "cashflow"}}
+	if got := <-requests; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Runner got %+v, want %+v", got, want)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != StatusDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %q", job.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+		_, body := get(t, ts.URL+"/api/scrapes/"+job.ID)
+		if err := json.Unmarshal([]byte(body), &job); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if job.Report == nil || job.Report.Parameters.Stocks != 2 || job.FinishedAt == nil {
+		t.Fatalf("finished job missing report: %+v", job)
+	}
+
+	_, body := get(t, ts.URL+"/api/scrapes")
+	var jobs []Job
+	if err := json.Unmarshal([]byte(body), &jobs); err != nil || len(jobs) != 1 {
+		t.Fatalf("GET /api/scrapes = %s (%v)", body, err)
+	}
+	if resp, _ := get(t, ts.URL+"/api/scrapes/99"); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("unknown scrape returned %d", resp.StatusCode)
+	}
+}
+
+func TestStocks(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+
+	_, body := get(t, ts.URL+"/api/stocks?group=semis")
+	var stocks []Stock
+	if err := json.Unmarshal([]byte(body), &stocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(stocks) != 1 || stocks[0].ID != "2330" || stocks[0].Name != "TSMC" {
+		t.Fatalf("unexpected stocks: %s", body)
+	}
+	per, cashflow := stocks[0].Datasets[0], stocks[0].Datasets[1]
+	if !per.Fresh || per.UpdatedAt == nil || cashflow.Fresh || cashflow.UpdatedAt != nil {
+		t.Fatalf("unexpected freshness: %s", body)
+	}
+
+	_, body = get(t, ts.URL+"/api/stocks")
+	if err := json.Unmarshal([]byte(body), &stocks); err != nil || len(stocks) != 2 {
+		t.Fatalf("GET /api/stocks = %s (%v)", body, err)
+	}
+	if resp, _ := get(t, ts.URL+"/api/stocks/x1"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid stock returned %d", resp.StatusCode)
+	}
+}
+
+func TestDataset(t *testing.T) {
+	_, ts := newTestServer(t, Options{})
+	tests := []struct {
+		path     string
+		status   int
+		contains string
+	}{
+		{"/api/stocks/2330/data/per", http.StatusOK, "24W02,\"1,090\",+10,+1.72%,-,18.3"},
+		{"/api/stocks/2330/data/per?format=json", http.StatusOK, `"stock_id":"2330"`},
+		{"/api/stocks/2330/data/per?format=ndjson", http.StatusOK, `"period":"24W02"`},
+		{"/api/stocks/2330/data/per?format=xlsx", http.StatusOK, "PK"},
+		{"/api/stocks/2330/data/per?format=pdf", http.StatusBadRequest, "invalid format"},
+		{"/api/stocks/2330/data/cashflow", http.StatusNotFound, "no cashflow data"},
+		{"/api/stocks/2330/data/dividends", http.StatusNotFound, "unknown dataset"},
+		{"/api/stocks/2330/combined", http.StatusNotFound, "no stockdata data"},
+		{"/api/stocks/2330/combined?lang=jp", http.StatusBadRequest, "invalid lang"},
+	}
+	for _, tt := range tests {
+		resp, body := get(t, ts.URL+tt.path)
+		if resp.StatusCode != tt.status || !strings.Contains(body, tt.contains) {
+			t.Errorf("GET %s = %d %q, want %d containing %q", tt.path, resp.StatusCode, body, tt.status, tt.contains)
+		}
+	}
+}
+
+func TestToken(t *testing.T) {
+	_, ts := newTestServer(t, Options{Token: "s3cret"})
+	if resp, _ := get(t, ts.URL+"/api/stocks"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("request without token returned %d", resp.StatusCode)
+	}
+	if resp, _ := get(t, ts.URL+"/api/health"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("health check returned %d", resp.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/stocks", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("request with token returned %d", resp.StatusCode)
+	}
+}
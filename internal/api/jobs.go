@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/flow"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/report"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+// Job statuses.
+const (
+	StatusQueued  = "queued"
+	StatusRunning = "running"
+	StatusDone    = "done"   // the run finished; its report tells which stocks failed
+	StatusFailed  = "failed" // the run could not be carried out
+)
+
+// QueueSize is how many scrape requests may wait for the running one.
+const QueueSize = 64
+
+// keepJobs is how many jobs GET /api/scrapes remembers; older finished jobs
+// are forgotten.
+const keepJobs = 100
+
+// dateLayout is the format of Request dates.
+const dateLayout = "2006-01-02"
+
+// Request asks for a scrape of some stocks.
+type Request struct {
+	Stocks []string `json:"stocks"`
+	// Scrapers defaults to Options.Scrapers.
+	Scrapers []string `json:"scrapers,omitempty"`
+	// StartDate and EndDate are both set, or both empty for the configured
+	// range.
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// Runner scrapes and combines the stocks of req and returns the run report.
+type Runner func(ctx context.Context, req Request) (*report.Report, error)
+
+// Job is a queued, running or finished scrape request.
+type Job struct {
+	ID         string         `json:"id"`
+	Status     string         `json:"status"`
+	Request    Request        `json:"request"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Report     *report.Report `json:"report,omitempty"`
+}
+
+// normalize validates req, upper-casing and de-duplicating its stocks and
+// filling in the default scrapers.
+func (req *Request) normalize(defaultScrapers []string) error {
+	var errs []error
+	if len(req.Stocks) == 0 {
+		errs = append(errs, errors.New("stocks must list at least one stock"))
+	}
+	var stocks []string
+	for _, id := range req.Stocks {
+		id = strings.ToUpper(strings.TrimSpace(id))
+		switch {
+		case !flow.ValidStockID(id):
+			errs = append(errs, fmt.Errorf("invalid stock id %q", id))
+		case !slices.Contains(stocks, id):
+			stocks = append(stocks, id)
+		}
+	}
+	req.Stocks = stocks
+
+	if len(req.Scrapers) == 0 {
+		req.Scrapers = defaultScrapers
+	}
+	for i, name := range req.Scrapers {
+		if _, err := storage.LookupDataset(name); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"unknown scraper %q, must be one of %s", name, strings.Join(storage.DatasetNames(), ", "),
+			))
+		} else if slices.Index(req.Scrapers, name) != i {
+			errs = append(errs, fmt.Errorf("scraper %q listed twice", name))
+		}
+	}
+
+	if (req.StartDate == "") != (req.EndDate == "") {
+		errs = append(errs, errors.New("start_date and end_date must be set together"))
+	} else if req.StartDate != "" {
+		start, err1 := time.Parse(dateLayout, req.StartDate)
+		end, err2 := time.Parse(dateLayout, req.EndDate)
+		switch {
+		case err1 != nil:
+			errs = append(errs, fmt.Errorf("invalid start_date %q, want YYYY-MM-DD", req.StartDate))
+		case err2 != nil:
+			errs = append(errs, fmt.Errorf("invalid end_date %q, want YYYY-MM-DD", req.EndDate))
+		case end.Before(start):
+			errs = append(errs, fmt.Errorf("end_date %s is before start_date %s", req.EndDate, req.StartDate))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// errQueueFull is returned by enqueue when QueueSize requests are waiting.
+var errQueueFull = errors.New("too many scrapes queued; try again later")
+
+// enqueue adds a job for req to the queue.
+func (s *Server) enqueue(req Request) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	job := &Job{
+		ID:        strconv.Itoa(s.nextID),
+		Status:    StatusQueued,
+		Request:   req,
+		CreatedAt: time.Now(),
+	}
+	select {
+	case s.queue <- job:
+	default:
+		return Job{}, errQueueFull
+	}
+	s.jobs = append(s.jobs, job)
+	if len(s.jobs) > keepJobs {
+		if i := slices.IndexFunc(s.jobs, func(j *Job) bool { return j.FinishedAt != nil }); i >= 0 {
+			s.jobs = slices.Delete(s.jobs, i, i+1)
+		}
+	}
+	return *job, nil
+}
+
+// job returns a copy of the job with the given ID.
+func (s *Server) job(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return *job, true
+		}
+	}
+	return Job{}, false
+}
+
+// allJobs returns copies of the remembered jobs, newest first.
+func (s *Server) allJobs() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, len(s.jobs))
+	for i, job := range s.jobs {
+		jobs[len(jobs)-1-i] = *job
+	}
+	return jobs
+}
+
+// Run carries out the queued scrapes one at a time until ctx is cancelled.
+// Scrapes still queued then are dropped.
+func (s *Server) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.run(ctx, job)
+		}
+	}
+}
+
+// run carries out one job with the Runner.
+func (s *Server) run(ctx context.Context, job *Job) {
+	s.mu.Lock()
+	started := time.Now()
+	job.Status, job.StartedAt = StatusRunning, &started
+	req := job.Request
+	s.mu.Unlock()
+
+	slog.Info("Starting requested scrape.", "job", job.ID, "stocks", strings.Join(req.Stocks, ","))
+	rep, err := s.opts.Run(ctx, req)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	job.FinishedAt, job.Report = &finished, rep
+	if err != nil {
+		job.Status, job.Error = StatusFailed, err.Error()
+		slog.Error("Requested scrape failed.", "job", job.ID, logging.KeyError, err)
+		return
+	}
+	job.Status = StatusDone
+	slog.Info("Requested scrape finished.", "job", job.ID, logging.KeyDuration, finished.Sub(started))
+}
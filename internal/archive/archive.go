@@ -0,0 +1,274 @@
+// Package archive writes dated, compressed snapshots of the scraped data,
+// prunes them by a retention policy and opens them again for comparison.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Formats lists the accepted archive formats.
+var Formats = []string{"zip", "tar.zst"}
+
+// ManifestFile is the name of the manifest at the root of every archive.
+const ManifestFile = "manifest.json"
+
+// Options describes one snapshot.
+type Options struct {
+	// Date names the snapshot directory and files, as YYYY-MM-DD.
+	Date string
+	// Format is one of Formats.
+	Format   string
+	RawDir   string
+	FinalDir string
+	// Run is the JSON report of the run that produced the data, included in
+	// each manifest; nil when there is none.
+	Run json.RawMessage
+}
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Date      string    `json:"date"`
+	// Source is the archived directory.
+	Source string          `json:"source"`
+	Files  []FileEntry     `json:"files"`
+	Run    json.RawMessage `json:"run,omitempty"`
+}
+
+// FileEntry is one archived file.
+type FileEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Snapshot archives opts.RawDir and opts.FinalDir into
+// archiveRoot/<date>/raw-<date>.<ext> and combined-<date>.<ext>, replacing a
+// snapshot of the same date. It returns the paths of the archives written.
+// A failed snapshot of a new date removes its directory, so that Prune does
+// not count it.
+func Snapshot(archiveRoot string, opts Options) (written []string, err error) {
+	dest := filepath.Join(archiveRoot, opts.Date)
+	if _, statErr := os.Stat(dest); errors.Is(statErr, os.ErrNotExist) {
+		defer func() {
+			if err != nil {
+				os.RemoveAll(dest)
+				written = nil
+			}
+		}()
+	}
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+
+	targets := []struct {
+		prefix string
+		src    string
+	}{
+		{"raw", opts.RawDir},
+		{"combined", opts.FinalDir},
+	}
+	for _, t := range targets {
+		path := filepath.Join(dest, fmt.Sprintf("%s-%s.%s", t.prefix, opts.Date, opts.Format))
+		manifest := Manifest{Date: opts.Date, Source: t.src, Run: opts.Run}
+		if err := WriteDir(t.src, path, opts.Format, manifest); err != nil {
+			return written, fmt.Errorf("failed to archive %s: %w", t.src, err)
+		}
+		written = append(written, path)
+		for _, format := range Formats {
+			if format == opts.Format {
+				continue
+			}
+			stale := filepath.Join(dest, fmt.Sprintf("%s-%s.%s", t.prefix, opts.Date, format))
+			if err := os.Remove(stale); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// WriteDir writes every file under srcDir into a new archive at path in the
+// given format, followed by manifest.json listing them with their sizes and
+// checksums. Entries are stored relative to srcDir's parent, so the archive
+// unpacks into a folder named after srcDir next to the manifest. The archive
+// is written to a temporary file next to path and renamed into place once
+// complete, so a failed write leaves an existing archive at path intact.
+func WriteDir(srcDir, path, format string, manifest Manifest) error {
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", srcDir)
+	}
+
+	out, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			out.Close()
+			os.Remove(out.Name())
+		}
+	}()
+
+	var w writer
+	switch format {
+	case "zip":
+		w = newZipWriter(out)
+	case "tar.zst":
+		w, err = newTarZstWriter(out)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown archive format %q", format)
+	}
+
+	base := filepath.Dir(filepath.Clean(srcDir))
+	err = filepath.WalkDir(srcDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		entry, err := addFile(w, path, filepath.ToSlash(rel))
+		manifest.Files = append(manifest.Files, entry)
+		return err
+	})
+	if err == nil {
+		err = addManifest(w, manifest)
+	}
+	if err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// writer adds files to an archive.
+type writer interface {
+	Add(name string, size int64, modTime time.Time, r io.Reader) error
+	Close() error
+}
+
+func addFile(w writer, path, name string) (FileEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return FileEntry{}, err
+	}
+	h := sha256.New()
+	if err := w.Add(name, info.Size(), info.ModTime(), io.TeeReader(f, h)); err != nil {
+		return FileEntry{}, err
+	}
+	return FileEntry{Path: name, Size: info.Size(), SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func addManifest(w writer, m Manifest) error {
+	m.CreatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	return w.Add(ManifestFile, int64(len(data)), m.CreatedAt, bytes.NewReader(data))
+}
+
+type zipWriter struct {
+	zw *zip.Writer
+}
+
+func newZipWriter(w io.Writer) *zipWriter {
+	return &zipWriter{zw: zip.NewWriter(w)}
+}
+
+func (z *zipWriter) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	w, err := z.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+func (z *zipWriter) Close() error {
+	return z.zw.Close()
+}
+
+// tarZstWriter writes a tar stream compressed with Zstandard, which packs
+// the many similar CSV files far tighter than zip's per-file deflate.
+type tarZstWriter struct {
+	zw *zstd.Encoder
+	tw *tar.Writer
+}
+
+func newTarZstWriter(w io.Writer) (*tarZstWriter, error) {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	return &tarZstWriter{zw: zw, tw: tar.NewWriter(zw)}, nil
+}
+
+func (t *tarZstWriter) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	err := t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0o644,
+		ModTime:  modTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(t.tw, r, size)
+	return err
+}
+
+func (t *tarZstWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		t.zw.Close()
+		return err
+	}
+	return t.zw.Close()
+}
@@ -0,0 +1,268 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// writeTree creates the download and final output directories of a small run
+// under root.
+func writeTree(t *testing.T, root string) (rawDir, finalDir string) {
+	t.Helper()
+	rawDir = filepath.Join(root, "downloaded_stock")
+	finalDir = filepath.Join(root, "final_output")
+	for _, path := range []string{
+		filepath.Join(rawDir, "2330", "per.csv"),
+		filepath.Join(rawDir, "2317", "per.csv"),
+		filepath.Join(finalDir, "2330.csv"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("24W01,1\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rawDir, finalDir
+}
+
+func TestSnapshot(t *testing.T) {
+	root := t.TempDir()
+	rawDir, finalDir := writeTree(t, root)
+
+	archiveRoot := filepath.Join(root, "archives")
+	files, err := Snapshot(archiveRoot, Options{
+		Date:     "2025-01-10",
+		Format:   "zip",
+		RawDir:   rawDir,
+		FinalDir: finalDir,
+		Run:      json.RawMessage(`{"summary":{"stocks_failed":0}}`),
+	})
+	if err != nil {
+		t.Fatalf("Snapshot returned error: %v", err)
+	}
+	want := []string{
+		filepath.Join(archiveRoot, "2025-01-10", "raw-2025-01-10.zip"),
+		filepath.Join(archiveRoot, "2025-01-10", "combined-2025-01-10.zip"),
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("Snapshot() files = %v, want %v", files, want)
+	}
+
+	zr, err := zip.OpenReader(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	var (
+		names    []string
+		manifest Manifest
+	)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		if f.Name != ManifestFile {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(r).Decode(&manifest)
+		r.Close()
+		if err != nil {
+			t.Fatalf("failed to decode manifest: %v", err)
+		}
+	}
+	sort.Strings(names)
+	wantNames := []string{"downloaded_stock/2317/per.csv", "downloaded_stock/2330/per.csv", ManifestFile}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("raw archive entries = %v, want %v", names, wantNames)
+	}
+
+	var run bytes.Buffer
+	if err := json.Compact(&run, manifest.Run); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Date != "2025-01-10" || manifest.Source != rawDir || run.String() != `{"summary":{"stocks_failed":0}}` {
+		t.Errorf("unexpected manifest %+v", manifest)
+	}
+	sum := sha256.Sum256([]byte("24W01,1\n"))
+	wantEntry := FileEntry{Path: "downloaded_stock/2317/per.csv", Size: 8, SHA256: hex.EncodeToString(sum[:])}
+	if len(manifest.Files) != 2 || manifest.Files[0] != wantEntry {
+		t.Errorf("manifest files = %+v, want two starting with %+v", manifest.Files, wantEntry)
+	}
+}
+
+func TestSnapshotTarZst(t *testing.T) {
+	root := t.TempDir()
+	rawDir, finalDir := writeTree(t, root)
+	archiveRoot := filepath.Join(root, "archives")
+	opts := Options{Date: "2025-01-10", Format: "zip", RawDir: rawDir, FinalDir: finalDir}
+	if _, err := Snapshot(archiveRoot, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Archiving the same date again in another format replaces the snapshot.
+	opts.Format = "tar.zst"
+	files, err := Snapshot(archiveRoot, opts)
+	if err != nil {
+		t.Fatalf("Snapshot returned error: %v", err)
+	}
+	entries, err := os.ReadDir(filepath.Join(archiveRoot, "2025-01-10"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"combined-2025-01-10.tar.zst", "raw-2025-01-10.tar.zst"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("snapshot files = %v, want %v", names, want)
+	}
+
+	dir, cleanup, err := Open(files[0])
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer cleanup()
+	if filepath.Base(dir) != "downloaded_stock" {
+		t.Errorf("Open() = %s, want the downloaded_stock folder", dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "2330", "per.csv"))
+	if err != nil || string(data) != "24W01,1\n" {
+		t.Errorf("unpacked per.csv = %q, %v", data, err)
+	}
+	checkModTime(t, filepath.Join(rawDir, "2330", "per.csv"), filepath.Join(dir, "2330", "per.csv"))
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), ManifestFile)); err != nil {
+		t.Errorf("expected manifest next to the unpacked folder: %v", err)
+	}
+}
+
+// checkModTime checks that the unpacked file keeps the original's
+// modification time.
+func checkModTime(t *testing.T, original, unpacked string) {
+	t.Helper()
+	want, err := os.Stat(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.Stat(unpacked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Archives store whole seconds.
+	if d := got.ModTime().Sub(want.ModTime()); d < -time.Second || d > time.Second {
+		t.Errorf("unpacked %s modified %v, want %v", unpacked, got.ModTime(), want.ModTime())
+	}
+}
+
+func TestWriteDirMissingSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteDir(filepath.Join(dir, "missing"), filepath.Join(dir, "out.zip"), "zip", Manifest{}); err == nil {
+		t.Fatal("expected error for missing source directory")
+	}
+}
+
+func TestOpen(t *testing.T) {
+	root := t.TempDir()
+	rawDir, _ := writeTree(t, root)
+	zipPath := filepath.Join(root, "raw.zip")
+	if err := WriteDir(rawDir, zipPath, "zip", Manifest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, cleanup, err := Open(rawDir)
+	if err != nil || dir != rawDir {
+		t.Fatalf("Open(dir) = %s, %v, want the directory itself", dir, err)
+	}
+	cleanup()
+
+	dir, cleanup, err = Open(zipPath)
+	if err != nil {
+		t.Fatalf("Open(zip) returned error: %v", err)
+	}
+	if filepath.Base(dir) != "downloaded_stock" {
+		t.Errorf("Open(zip) = %s, want the downloaded_stock folder", dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "2330", "per.csv"))
+	if err != nil || string(data) != "24W01,1\n" {
+		t.Errorf("unpacked per.csv = %q, %v", data, err)
+	}
+	checkModTime(t, filepath.Join(rawDir, "2330", "per.csv"), filepath.Join(dir, "2330", "per.csv"))
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected cleanup to remove %s", dir)
+	}
+
+	if _, _, err := Open(filepath.Join(root, "raw.rar")); err == nil {
+		t.Error("expected error for missing archive")
+	}
+}
+
+func TestEntryPath(t *testing.T) {
+	dest := t.TempDir()
+	if _, err := entryPath(dest, "downloaded_stock/2330/per.csv"); err != nil {
+		t.Errorf("entryPath returned error for a plain entry: %v", err)
+	}
+	if _, err := entryPath(dest, "../../etc/passwd"); err == nil {
+		t.Error("expected error for an entry escaping the destination")
+	}
+}
+
+func TestWriteDirKeepsArchiveOnFailure(t *testing.T) {
+	root := t.TempDir()
+	rawDir, _ := writeTree(t, root)
+	dest := filepath.Join(root, "archives")
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dest, "raw.zip")
+	if err := WriteDir(rawDir, path, "zip", Manifest{}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WriteDir(rawDir, path, "rar", Manifest{}); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	after, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(after, before) {
+		t.Errorf("existing archive changed by a failed write: %v", err)
+	}
+	entries, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the archive in %s, found %d entries", dest, len(entries))
+	}
+}
+
+func TestSnapshotFailureLeavesNoDirectory(t *testing.T) {
+	root := t.TempDir()
+	archiveRoot := filepath.Join(root, "archives")
+	_, err := Snapshot(archiveRoot, Options{
+		Date:     "2025-01-10",
+		Format:   "zip",
+		RawDir:   filepath.Join(root, "missing"),
+		FinalDir: filepath.Join(root, "missing"),
+	})
+	if err == nil {
+		t.Fatal("expected error for missing directories")
+	}
+	if _, err := os.Stat(filepath.Join(archiveRoot, "2025-01-10")); !os.IsNotExist(err) {
+		t.Errorf("expected the failed snapshot's directory to be removed: %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Open returns a directory holding the snapshot at path, which is either a
+// directory or an archive written by Snapshot. Archives are unpacked into a
+// temporary directory that cleanup removes; the directory returned is the
+// folder the archive unpacks into, such as downloaded_stock/.
+func Open(path string) (dir string, cleanup func(), err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return path, func() {}, nil
+	}
+
+	tmp, err := os.MkdirTemp("", "scraper-snapshot-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.RemoveAll(tmp) }
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		err = unzip(path, tmp)
+	case strings.HasSuffix(path, ".tar.zst"):
+		err = untarZst(path, tmp)
+	default:
+		err = fmt.Errorf("unsupported archive %s: want a directory, .zip or .tar.zst", path)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	// Archives hold a single top-level folder named after the archived
+	// directory, next to the manifest; return that folder.
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name())
+		} else if entry.Name() != ManifestFile {
+			return tmp, cleanup, nil
+		}
+	}
+	if len(dirs) == 1 {
+		return filepath.Join(tmp, dirs[0]), cleanup, nil
+	}
+	return tmp, cleanup, nil
+}
+
+// unzip extracts the zip archive at path into dest.
+func unzip(path, dest string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		target, err := entryPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(target, r, f.Modified)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untarZst extracts the Zstandard-compressed tar archive at path into dest.
+func untarZst(path, dest string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		target, err := entryPath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(target, tr, header.ModTime); err != nil {
+			return err
+		}
+	}
+}
+
+// entryPath joins an archive entry name onto dest, rejecting names that
+// would escape it.
+func entryPath(dest, name string) (string, error) {
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid archive entry %q", name)
+	}
+	return target, nil
+}
+
+// writeFile copies r into a new file at target, creating its directory, and
+// gives it the archived modification time, which ReadDataset relies on to
+// pick between files of several formats.
+func writeFile(target string, r io.Reader, modTime time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, modTime, modTime)
+}
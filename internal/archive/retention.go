@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// dateLayout is the format of snapshot directory names.
+const dateLayout = "2006-01-02"
+
+// Retention says how many snapshots to keep: the newest Daily days, plus the
+// newest snapshot of each of the last Weekly ISO weeks and of each of the
+// last Monthly months that have one. All zero keeps everything.
+type Retention struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// Zero reports whether r keeps every snapshot.
+func (r Retention) Zero() bool {
+	return r.Daily == 0 && r.Weekly == 0 && r.Monthly == 0
+}
+
+// Snapshots returns the dates of the snapshot directories under archiveRoot,
+// newest first. Entries whose names are not YYYY-MM-DD dates are ignored.
+func Snapshots(archiveRoot string) ([]time.Time, error) {
+	entries, err := os.ReadDir(archiveRoot)
+	if err != nil {
+		return nil, err
+	}
+	var dates []time.Time
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if date, err := time.Parse(dateLayout, entry.Name()); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return b.Compare(a) })
+	return dates, nil
+}
+
+// Keep returns the dates r keeps out of dates, which must be newest first.
+func (r Retention) Keep(dates []time.Time) map[time.Time]bool {
+	keep := make(map[time.Time]bool)
+	if r.Zero() {
+		for _, date := range dates {
+			keep[date] = true
+		}
+		return keep
+	}
+	bucket := func(n int, period func(time.Time) int) {
+		last, count := -1, 0
+		for _, date := range dates {
+			if count >= n {
+				return
+			}
+			if p := period(date); p != last {
+				keep[date] = true
+				last = p
+				count++
+			}
+		}
+	}
+	bucket(r.Daily, func(t time.Time) int { return t.Year()*1000 + t.YearDay() })
+	bucket(r.Weekly, func(t time.Time) int {
+		year, week := t.ISOWeek()
+		return year*100 + week
+	})
+	bucket(r.Monthly, func(t time.Time) int { return t.Year()*100 + int(t.Month()) })
+	return keep
+}
+
+// Prune removes the snapshot directories under archiveRoot that r does not
+// keep and returns their paths. A missing archiveRoot has nothing to prune.
+func Prune(archiveRoot string, r Retention) ([]string, error) {
+	if r.Zero() {
+		return nil, nil
+	}
+	dates, err := Snapshots(archiveRoot)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	keep := r.Keep(dates)
+	var removed []string
+	for _, date := range dates {
+		if keep[date] {
+			continue
+		}
+		dir := filepath.Join(archiveRoot, date.Format(dateLayout))
+		if err := os.RemoveAll(dir); err != nil {
+			return removed, err
+		}
+		removed = append(removed, dir)
+	}
+	return removed, nil
+}
@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionKeep(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse(dateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	// Newest first: two weeks of dailies in January, then month ends.
+	var dates []time.Time
+	for _, s := range []string{
+		"2025-01-15", "2025-01-14", "2025-01-13", "2025-01-10", "2025-01-09", "2025-01-06",
+		"2024-12-31", "2024-12-30", "2024-11-30", "2024-10-31",
+	} {
+		dates = append(dates, day(s))
+	}
+
+	tests := []struct {
+		name string
+		keep Retention
+		want []string
+	}{
+		{"zero keeps all", Retention{}, []string{
+			"2025-01-15", "2025-01-14", "2025-01-13", "2025-01-10", "2025-01-09", "2025-01-06",
+			"2024-12-31", "2024-12-30", "2024-11-30", "2024-10-31",
+		}},
+		{"daily", Retention{Daily: 2}, []string{"2025-01-15", "2025-01-14"}},
+		// 2024-12-30 and 2024-12-31 share ISO week 2025-W01 with 2025-01-03.
+		{"weekly", Retention{Weekly: 3}, []string{"2025-01-15", "2025-01-10", "2024-12-31"}},
+		{"monthly", Retention{Monthly: 3}, []string{"2025-01-15", "2024-12-31", "2024-11-30"}},
+		{"combined", Retention{Daily: 1, Weekly: 2, Monthly: 4}, []string{
+			"2025-01-15", "2025-01-10", "2024-12-31", "2024-11-30", "2024-10-31",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keep := tt.keep.Keep(dates)
+			var got []string
+			for _, d := range dates {
+				if keep[d] {
+					got = append(got, d.Format(dateLayout))
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Keep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"2025-01-15", "2025-01-14", "2025-01-13", "notes"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := Prune(root, Retention{Daily: 2})
+	if err != nil {
+		t.Fatalf("Prune returned error: %v", err)
+	}
+	if want := []string{filepath.Join(root, "2025-01-13")}; !reflect.DeepEqual(removed, want) {
+		t.Errorf("Prune() removed %v, want %v", removed, want)
+	}
+	for _, name := range []string{"2025-01-15", "2025-01-14", "notes"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+			t.Errorf("expected %s to be kept: %v", name, err)
+		}
+	}
+
+	if removed, err := Prune(filepath.Join(root, "missing"), Retention{Daily: 1}); err != nil || removed != nil {
+		t.Errorf("Prune(missing) = %v, %v, want nothing", removed, err)
+	}
+}
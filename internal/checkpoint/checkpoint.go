@@ -0,0 +1,246 @@
+// Package checkpoint keeps a ledger of the tasks of a scrape run on disk, so
+// a run that dies can be resumed where it stopped.
+//
+// The ledger is a JSON Lines file: a header record describing the run,
+// followed by one record per task state change and a final record once the
+// run is over. Records are appended as they happen, so the file survives the
+// process being killed at any point; a torn last line is ignored.
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is the progress of one task.
+type State string
+
+const (
+	Pending State = "pending"
+	Running State = "running"
+	Done    State = "done"
+	Failed  State = "failed"
+)
+
+// Task is one scraper type of one stock over one date range.
+type Task struct {
+	Stock     string `json:"stock"`
+	Scraper   string `json:"scraper"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// Run describes the tasks of a run: every scraper of every stock over the
+// date range.
+type Run struct {
+	StartedAt time.Time `json:"started_at"`
+	StartDate string    `json:"start_date"`
+	EndDate   string    `json:"end_date"`
+	Stocks    []string  `json:"stocks"`
+	Scrapers  []string  `json:"scrapers"`
+}
+
+// Tasks lists the tasks of the run, stock by stock.
+func (r Run) Tasks() []Task {
+	tasks := make([]Task, 0, len(r.Stocks)*len(r.Scrapers))
+	for _, stock := range r.Stocks {
+		for _, scraper := range r.Scrapers {
+			tasks = append(tasks, Task{stock, scraper, r.StartDate, r.EndDate})
+		}
+	}
+	return tasks
+}
+
+// record is one line of the ledger file; exactly one of Run, Task or
+// Finished is set.
+type record struct {
+	At       time.Time `json:"at"`
+	Run      *Run      `json:"run,omitempty"`
+	Task     *Task     `json:"task,omitempty"`
+	State    State     `json:"state,omitempty"`
+	Finished bool      `json:"finished,omitempty"`
+}
+
+// Ledger records the state of every task of a run. It is safe for concurrent
+// use, and a nil *Ledger ignores every call.
+type Ledger struct {
+	mu       sync.Mutex
+	file     *os.File
+	run      Run
+	states   map[Task]State
+	finished bool
+}
+
+// Create starts a new ledger at path for run, replacing any previous one.
+func Create(path string, run Run) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	l := &Ledger{file: file, run: run, states: make(map[Task]State)}
+	if err := l.write(record{At: time.Now(), Run: &run}); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Open loads the ledger at path and appends further changes to it. Tasks
+// that were running when the previous process stopped are pending again.
+func Open(path string) (*Ledger, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no checkpoint at %s: %w", path, err)
+		}
+		return nil, err
+	}
+	l, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+	l.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func parse(data []byte) (*Ledger, error) {
+	l := &Ledger{states: make(map[Task]State)}
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		lineNo := i + 1
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			if i == len(lines)-1 && lineNo > 1 {
+				break // torn last line of a killed process
+			}
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		switch {
+		case rec.Run != nil:
+			if lineNo != 1 {
+				return nil, fmt.Errorf("line %d: unexpected run header", lineNo)
+			}
+			l.run = *rec.Run
+		case lineNo == 1:
+			return nil, errors.New("missing run header")
+		case rec.Task != nil:
+			state := rec.State
+			if state == Running {
+				state = Pending
+			}
+			l.states[*rec.Task] = state
+		case rec.Finished:
+			l.finished = true
+		}
+	}
+	if l.run.StartedAt.IsZero() {
+		return nil, errors.New("missing run header")
+	}
+	return l, nil
+}
+
+func (l *Ledger) write(rec record) error {
+	if l.file == nil {
+		return errors.New("checkpoint is closed")
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Run returns the run the ledger belongs to.
+func (l *Ledger) Run() Run {
+	return l.run
+}
+
+// State returns the state of task; tasks never marked are Pending.
+func (l *Ledger) State(task Task) State {
+	if l == nil {
+		return Pending
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.states[task]; ok {
+		return s
+	}
+	return Pending
+}
+
+// Mark records the new state of task.
+func (l *Ledger) Mark(task Task, state State) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.states[task] = state
+	return l.write(record{At: time.Now(), Task: &task, State: state})
+}
+
+// Counts returns the number of tasks of the run in each state.
+func (l *Ledger) Counts() map[State]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counts := make(map[State]int)
+	for _, task := range l.run.Tasks() {
+		s, ok := l.states[task]
+		if !ok {
+			s = Pending
+		}
+		counts[s]++
+	}
+	return counts
+}
+
+// Finished reports whether the run completed, successfully or not.
+func (l *Ledger) Finished() bool {
+	return l.finished
+}
+
+// Finish records that the run completed and closes the ledger.
+func (l *Ledger) Finish() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	l.finished = true
+	err := l.write(record{At: time.Now(), Finished: true})
+	l.mu.Unlock()
+	return errors.Join(err, l.Close())
+}
+
+// Close closes the ledger file, leaving the run resumable. Closing a closed
+// ledger does nothing.
+func (l *Ledger) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
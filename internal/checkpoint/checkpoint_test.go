@@ -0,0 +1,125 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testRun() Run {
+	return Run{
+		StartedAt: time.Date(2024, 5, 1, 23, 30, 0, 0, time.UTC),
+		StartDate: "1965-01-01",
+		EndDate:   "2024-05-01",
+		Stocks:    []string{"2330", "2317"},
+		Scrapers:  []string{"per", "cashflow"},
+	}
+}
+
+func TestLedgerResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "checkpoint.jsonl")
+	run := testRun()
+	tasks := run.Tasks()
+
+	l, err := Create(path, run)
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	for task, state := range map[Task]State{tasks[0]: Done, tasks[1]: Failed, tasks[2]: Running} {
+		if err := l.Mark(task, state); err != nil {
+			t.Fatalf("Mark returned error: %v", err)
+		}
+	}
+	// The process dies without finishing the run.
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	defer l.Close()
+	if !reflect.DeepEqual(l.Run().Stocks, run.Stocks) || l.Run().EndDate != run.EndDate {
+		t.Fatalf("unexpected run: %+v", l.Run())
+	}
+	if l.Finished() {
+		t.Fatalf("expected an unfinished run")
+	}
+	want := map[State]int{Done: 1, Failed: 1, Pending: 2}
+	if got := l.Counts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Counts() = %v, want %v", got, want)
+	}
+	if got := l.State(tasks[2]); got != Pending {
+		t.Fatalf("expected an interrupted task to be pending, got %s", got)
+	}
+	other := Task{Stock: "2330", Scraper: "per", StartDate: "2020-01-01", EndDate: "2024-05-01"}
+	if got := l.State(other); got != Pending {
+		t.Fatalf("expected a task over another range to be pending, got %s", got)
+	}
+
+	if err := l.Mark(tasks[3], Done); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Finish(); err != nil {
+		t.Fatalf("Finish returned error: %v", err)
+	}
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if !l.Finished() || l.State(tasks[3]) != Done {
+		t.Fatalf("expected the finished run to be recorded")
+	}
+}
+
+func TestOpenTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	l, err := Create(path, testRun())
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := testRun().Tasks()[0]
+	if err := l.Mark(task, Done); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"at":"2024-05-02T00:01:00Z","task":{"stock":"23`)
+	f.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error for a torn last line: %v", err)
+	}
+	defer l.Close()
+	if l.State(task) != Done {
+		t.Fatalf("expected records before the torn line to be kept")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Open(filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Errorf("expected error for a missing checkpoint")
+	}
+	path := filepath.Join(dir, "bad.jsonl")
+	os.WriteFile(path, []byte(`{"at":"2024-05-01T00:00:00Z","state":"done"}`+"\n"), 0o644)
+	if _, err := Open(path); err == nil {
+		t.Errorf("expected error for a checkpoint without a run header")
+	}
+}
+
+func TestNilLedger(t *testing.T) {
+	var l *Ledger
+	if l.State(testRun().Tasks()[0]) != Pending || l.Mark(testRun().Tasks()[0], Done) != nil || l.Finish() != nil {
+		t.Fatalf("expected a nil ledger to ignore calls")
+	}
+}
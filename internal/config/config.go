@@ -0,0 +1,690 @@
+// Package config loads the scraper's settings from a YAML file and the
+// environment. Settings are layered: built-in defaults, then the config file,
+// then SCRAPER_* environment variables, then command-line flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ysonC/multi-stocks-download/internal/archive"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/notify"
+	"github.com/ysonC/multi-stocks-download/internal/progress"
+	"github.com/ysonC/multi-stocks-download/internal/schedule"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+// EnvPath names the environment variable holding the config file path used
+// when -config is not given.
+const EnvPath = "SCRAPER_CONFIG"
+
+// dateLayout is the format of StartDate and EndDate.
+const dateLayout = "2006-01-02"
+
+// Stores lists the accepted Output.Store values.
+var Stores = []string{"csv", "sqlite", "both"}
+
+// Orders lists the accepted Order policies: input keeps the input order
+// (interleaved across scraper types), priority puts PriorityGroups first,
+// staleness puts the oldest data first, failed puts the previous run's
+// failures last and random shuffles the queue.
+var Orders = []string{"input", "priority", "staleness", "failed", "random"}
+
+// Config holds every setting of a scraper run.
+type Config struct {
+	Workers int `yaml:"workers"`
+	// StartDate and EndDate bound the scraped history; both empty means the
+	// full range (see DateRange).
+	StartDate string `yaml:"start_date"`
+	EndDate   string `yaml:"end_date"`
+	// Scrapers lists the datasets to scrape.
+	Scrapers []string `yaml:"scrapers"`
+	// Groups limits runs to the input stocks tagged with any of these
+	// groups; empty means every stock.
+	Groups []string `yaml:"groups,omitempty"`
+	// Order lists the policies ordering the task queue, the first deciding
+	// and the others breaking ties; see Orders.
+	Order []string `yaml:"order"`
+	// PriorityGroups ranks input groups for the priority policy, most
+	// urgent first.
+	PriorityGroups []string `yaml:"priority_groups,omitempty"`
+	Dirs           Dirs     `yaml:"dirs"`
+	Output         Output   `yaml:"output"`
+	Log            Log      `yaml:"log"`
+	// Progress is one of progress.Modes.
+	Progress string `yaml:"progress"`
+	// MetricsAddr is the listen address of the Prometheus /metrics endpoint
+	// of scrape runs and serve, such as ":9090"; empty disables it.
+	MetricsAddr string `yaml:"metrics_addr,omitempty"`
+	// ScraperDefaults applies to every scraper type; ScraperOptions overrides
+	// it per type.
+	ScraperDefaults ScraperOptions             `yaml:"scraper_defaults"`
+	ScraperOptions  map[string]ScraperOverride `yaml:"scraper_options,omitempty"`
+	Daemon          Daemon                     `yaml:"daemon,omitempty"`
+	Notify          Notify                     `yaml:"notify,omitempty"`
+	Archive         Archive                    `yaml:"archive"`
+}
+
+// Dirs holds the directories the scraper reads from and writes to. Every
+// directory left empty is a fixed subdirectory of Data (see Resolve), so
+// moving Data moves them all.
+type Dirs struct {
+	Data        string `yaml:"data"`
+	Input       string `yaml:"input,omitempty"`
+	Download    string `yaml:"download,omitempty"`
+	FinalOutput string `yaml:"final_output,omitempty"`
+	Failed      string `yaml:"failed,omitempty"`
+	Export      string `yaml:"export,omitempty"`
+	Archive     string `yaml:"archive,omitempty"`
+	Report      string `yaml:"report,omitempty"`
+}
+
+// Default subdirectory names of Dirs.Data.
+const (
+	InputSubdir       = "input_stock"
+	DownloadSubdir    = "downloaded_stock"
+	FinalOutputSubdir = "final_output"
+	FailedSubdir      = "failed_stock"
+	ExportSubdir      = "export"
+	ArchiveSubdir     = "archives"
+	ReportSubdir      = "reports"
+	DBFile            = "scraper.db"
+	CheckpointFile    = "checkpoint.jsonl"
+	LockFile          = "scraper.lock"
+	UniverseFile      = "universe.csv"
+)
+
+// Resolve returns the directories with every empty entry replaced by its
+// default subdirectory of Data.
+func (d Dirs) Resolve() Dirs {
+	or := func(dir, subdir string) string {
+		if dir != "" {
+			return dir
+		}
+		return filepath.Join(d.Data, subdir)
+	}
+	return Dirs{
+		Data:        d.Data,
+		Input:       or(d.Input, InputSubdir),
+		Download:    or(d.Download, DownloadSubdir),
+		FinalOutput: or(d.FinalOutput, FinalOutputSubdir),
+		Failed:      or(d.Failed, FailedSubdir),
+		Export:      or(d.Export, ExportSubdir),
+		Archive:     or(d.Archive, ArchiveSubdir),
+		Report:      or(d.Report, ReportSubdir),
+	}
+}
+
+// Output selects where and in which formats scraped data is written.
+type Output struct {
+	// Store is one of Stores.
+	Store string `yaml:"store"`
+	// DB is the SQLite database used when Store is sqlite or both; empty
+	// means scraper.db in Dirs.Data (see Config.DBPath).
+	DB string `yaml:"db,omitempty"`
+	// Format is one of storage.FileFormats.
+	Format string `yaml:"format"`
+	// CombinedFormat is one of storage.CombinedFormats; empty means Format.
+	CombinedFormat string `yaml:"combined_format"`
+	// HeaderLang is one of storage.HeaderLangs.
+	HeaderLang string `yaml:"header_lang"`
+	// ReportHTML adds an HTML page next to each JSON run report.
+	ReportHTML bool `yaml:"report_html"`
+}
+
+// Log configures the structured log.
+type Log struct {
+	// Level is one of logging.Levels.
+	Level string `yaml:"level"`
+	// Format is one of logging.Formats.
+	Format string `yaml:"format"`
+	// File receives the log instead of stderr when set.
+	File string `yaml:"file,omitempty"`
+}
+
+// Combined returns the format of combined outputs.
+func (o Output) Combined() string {
+	if o.CombinedFormat == "" {
+		return o.Format
+	}
+	return o.CombinedFormat
+}
+
+// Daemon holds the jobs and the HTTP API of the serve command.
+type Daemon struct {
+	// Timezone is the IANA zone the schedules are read in; empty means the
+	// local time zone.
+	Timezone string `yaml:"timezone,omitempty"`
+	Jobs     []Job  `yaml:"jobs,omitempty"`
+	// APIAddr is the listen address of the HTTP API, such as ":8080"; empty
+	// disables it.
+	APIAddr string `yaml:"api_addr,omitempty"`
+	// APIToken, when set, is the bearer token every API request must carry.
+	APIToken string `yaml:"api_token,omitempty"`
+}
+
+// Job is one scheduled scrape.
+type Job struct {
+	Name string `yaml:"name"`
+	// Schedules lists cron expressions (see schedule.Parse); the job runs at
+	// the times of each.
+	Schedules []string `yaml:"schedules"`
+	// Scrapers and Groups replace the top-level settings for this job when
+	// set.
+	Scrapers []string `yaml:"scrapers,omitempty"`
+	Groups   []string `yaml:"groups,omitempty"`
+}
+
+// Location returns the time zone of the schedules.
+func (d Daemon) Location() (*time.Location, error) {
+	if d.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(d.Timezone)
+}
+
+// Notify configures the webhooks told about finished runs.
+type Notify struct {
+	// Always reports every run; otherwise only runs whose share of failed
+	// stocks exceeds FailureRate are reported.
+	Always bool `yaml:"always"`
+	// FailureRate is a fraction from 0 to 1.
+	FailureRate float64   `yaml:"failure_rate"`
+	Webhooks    []Webhook `yaml:"webhooks,omitempty"`
+}
+
+// Webhook is one notification target; see notify.Webhook.
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Format is one of notify.Formats.
+	Format   string            `yaml:"format"`
+	To       string            `yaml:"to,omitempty"`
+	Headers  map[string]string `yaml:"headers,omitempty"`
+	Template string            `yaml:"template,omitempty"`
+}
+
+// Archive configures the dated snapshots of Dirs.Download and
+// Dirs.FinalOutput written to Dirs.Archive.
+type Archive struct {
+	// Format is one of archive.Formats.
+	Format string `yaml:"format"`
+	// AfterScrape archives the data after every scrape run that was not
+	// interrupted.
+	AfterScrape bool `yaml:"after_scrape"`
+	// Keep prunes older snapshots after archiving; all zero keeps every
+	// snapshot.
+	Keep Keep `yaml:"keep,omitempty"`
+}
+
+// Keep is the retention policy of the archive; see archive.Retention.
+type Keep struct {
+	Daily   int `yaml:"daily,omitempty"`
+	Weekly  int `yaml:"weekly,omitempty"`
+	Monthly int `yaml:"monthly,omitempty"`
+}
+
+// ScraperOptions tunes one scraper type.
+type ScraperOptions struct {
+	// Timeout bounds the wait for the page's data table.
+	Timeout Duration `yaml:"timeout"`
+	// Freshness is how old stored data may be before it is scraped again;
+	// zero means it must have been saved today.
+	Freshness Duration `yaml:"freshness"`
+	// Retries is how many extra attempts a failed scrape gets.
+	Retries int `yaml:"retries"`
+}
+
+// ScraperOverride replaces the ScraperDefaults fields that are set.
+type ScraperOverride struct {
+	Timeout   *Duration `yaml:"timeout,omitempty"`
+	Freshness *Duration `yaml:"freshness,omitempty"`
+	Retries   *int      `yaml:"retries,omitempty"`
+}
+
+// Duration is a time.Duration written as "10s" or "12h" in YAML.
+type Duration time.Duration
+
+func (d Duration) MarshalYAML() (any, error) {
+	return time.Duration(d).String(), nil
+}
+
+func (d *Duration) UnmarshalYAML(node *yaml.Node) error {
+	v, err := time.ParseDuration(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", node.Line, node.Value)
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default returns the settings used when nothing is configured, matching the
+// scraper's historical behaviour.
+func Default() *Config {
+	return &Config{
+		Workers:  5,
+		Scrapers: storage.DatasetNames(),
+		Dirs:     Dirs{Data: "data"},
+		Output: Output{
+			Store:      "csv",
+			Format:     "csv",
+			HeaderLang: "zh",
+		},
+		Log:             Log{Level: "info", Format: "text"},
+		Progress:        "auto",
+		Order:           []string{"input"},
+		ScraperDefaults: ScraperOptions{Timeout: Duration(10 * time.Second)},
+		Archive:         Archive{Format: "zip"},
+	}
+}
+
+// Load returns the defaults overlaid with the YAML file at path. An empty
+// path returns the defaults. Unknown keys are rejected so typos surface.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// DBPath returns the SQLite database path: Output.DB, or scraper.db in the
+// data directory.
+func (c *Config) DBPath() string {
+	if c.Output.DB != "" {
+		return c.Output.DB
+	}
+	return filepath.Join(c.Dirs.Data, DBFile)
+}
+
+// CheckpointPath returns the task ledger of the latest run, kept in the data
+// directory so -resume finds it whatever the other directories are.
+func (c *Config) CheckpointPath() string {
+	return filepath.Join(c.Dirs.Data, CheckpointFile)
+}
+
+// LockPath returns the lock file that keeps two scrape runs of the same data
+// directory from overlapping.
+func (c *Config) LockPath() string {
+	return filepath.Join(c.Dirs.Data, LockFile)
+}
+
+// ApplyEnv overrides the settings with the SCRAPER_* environment variables
+// found by lookup (usually os.LookupEnv):
+//
+//	SCRAPER_WORKERS, SCRAPER_START_DATE, SCRAPER_END_DATE,
+//	SCRAPER_SCRAPERS, SCRAPER_GROUPS, SCRAPER_ORDER and
+//	SCRAPER_PRIORITY_GROUPS (comma-separated), SCRAPER_STORE, SCRAPER_DB,
+//	SCRAPER_FORMAT, SCRAPER_COMBINED_FORMAT, SCRAPER_HEADER_LANG,
+//	SCRAPER_TIMEOUT, SCRAPER_FRESHNESS, SCRAPER_RETRIES,
+//	SCRAPER_DATA_DIR, SCRAPER_INPUT_DIR, SCRAPER_DOWNLOAD_DIR,
+//	SCRAPER_FINAL_OUTPUT_DIR, SCRAPER_FAILED_DIR, SCRAPER_EXPORT_DIR,
+//	SCRAPER_ARCHIVE_DIR, SCRAPER_REPORT_DIR, SCRAPER_REPORT_HTML,
+//	SCRAPER_LOG_LEVEL, SCRAPER_LOG_FORMAT, SCRAPER_LOG_FILE, SCRAPER_PROGRESS,
+//	SCRAPER_API_ADDR, SCRAPER_API_TOKEN, SCRAPER_METRICS_ADDR,
+//	SCRAPER_ARCHIVE_FORMAT, SCRAPER_ARCHIVE_AFTER_SCRAPE,
+//	SCRAPER_ARCHIVE_KEEP_DAILY, SCRAPER_ARCHIVE_KEEP_WEEKLY,
+//	SCRAPER_ARCHIVE_KEEP_MONTHLY
+//
+// TIMEOUT, FRESHNESS and RETRIES set ScraperDefaults.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	strs := map[string]*string{
+		"SCRAPER_DATA_DIR":         &c.Dirs.Data,
+		"SCRAPER_INPUT_DIR":        &c.Dirs.Input,
+		"SCRAPER_DOWNLOAD_DIR":     &c.Dirs.Download,
+		"SCRAPER_FINAL_OUTPUT_DIR": &c.Dirs.FinalOutput,
+		"SCRAPER_FAILED_DIR":       &c.Dirs.Failed,
+		"SCRAPER_EXPORT_DIR":       &c.Dirs.Export,
+		"SCRAPER_ARCHIVE_DIR":      &c.Dirs.Archive,
+		"SCRAPER_REPORT_DIR":       &c.Dirs.Report,
+		"SCRAPER_START_DATE":       &c.StartDate,
+		"SCRAPER_END_DATE":         &c.EndDate,
+		"SCRAPER_STORE":            &c.Output.Store,
+		"SCRAPER_DB":               &c.Output.DB,
+		"SCRAPER_FORMAT":           &c.Output.Format,
+		"SCRAPER_COMBINED_FORMAT":  &c.Output.CombinedFormat,
+		"SCRAPER_HEADER_LANG":      &c.Output.HeaderLang,
+		"SCRAPER_LOG_LEVEL":        &c.Log.Level,
+		"SCRAPER_LOG_FORMAT":       &c.Log.Format,
+		"SCRAPER_LOG_FILE":         &c.Log.File,
+		"SCRAPER_PROGRESS":         &c.Progress,
+		"SCRAPER_API_ADDR":         &c.Daemon.APIAddr,
+		"SCRAPER_API_TOKEN":        &c.Daemon.APIToken,
+		"SCRAPER_METRICS_ADDR":     &c.MetricsAddr,
+		"SCRAPER_ARCHIVE_FORMAT":   &c.Archive.Format,
+	}
+	for name, field := range strs {
+		if v, ok := lookup(name); ok {
+			*field = v
+		}
+	}
+
+	var errs []error
+	ints := map[string]*int{
+		"SCRAPER_WORKERS":              &c.Workers,
+		"SCRAPER_RETRIES":              &c.ScraperDefaults.Retries,
+		"SCRAPER_ARCHIVE_KEEP_DAILY":   &c.Archive.Keep.Daily,
+		"SCRAPER_ARCHIVE_KEEP_WEEKLY":  &c.Archive.Keep.Weekly,
+		"SCRAPER_ARCHIVE_KEEP_MONTHLY": &c.Archive.Keep.Monthly,
+	}
+	for name, field := range ints {
+		if v, ok := lookup(name); ok {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid integer %q", name, v))
+				continue
+			}
+			*field = n
+		}
+	}
+	bools := map[string]*bool{
+		"SCRAPER_REPORT_HTML":          &c.Output.ReportHTML,
+		"SCRAPER_ARCHIVE_AFTER_SCRAPE": &c.Archive.AfterScrape,
+	}
+	for name, field := range bools {
+		if v, ok := lookup(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid boolean %q", name, v))
+				continue
+			}
+			*field = b
+		}
+	}
+	durations := map[string]*Duration{
+		"SCRAPER_TIMEOUT":   &c.ScraperDefaults.Timeout,
+		"SCRAPER_FRESHNESS": &c.ScraperDefaults.Freshness,
+	}
+	for name, field := range durations {
+		if v, ok := lookup(name); ok {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid duration %q", name, v))
+				continue
+			}
+			*field = Duration(d)
+		}
+	}
+	if v, ok := lookup("SCRAPER_SCRAPERS"); ok {
+		c.Scrapers = SplitList(v)
+	}
+	if v, ok := lookup("SCRAPER_GROUPS"); ok {
+		c.Groups = SplitList(v)
+	}
+	if v, ok := lookup("SCRAPER_ORDER"); ok {
+		c.Order = SplitList(v)
+	}
+	if v, ok := lookup("SCRAPER_PRIORITY_GROUPS"); ok {
+		c.PriorityGroups = SplitList(v)
+	}
+	return errors.Join(errs...)
+}
+
+// SplitList splits a comma-separated list, dropping blanks.
+func SplitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Workers <= 0 {
+		add("workers must be > 0, got %d", c.Workers)
+	}
+
+	if (c.StartDate == "") != (c.EndDate == "") {
+		add("start_date and end_date must be set together, or neither for the full range")
+	} else if c.StartDate != "" {
+		start, err1 := time.Parse(dateLayout, c.StartDate)
+		end, err2 := time.Parse(dateLayout, c.EndDate)
+		switch {
+		case err1 != nil:
+			add("invalid start_date %q, want YYYY-MM-DD", c.StartDate)
+		case err2 != nil:
+			add("invalid end_date %q, want YYYY-MM-DD", c.EndDate)
+		case end.Before(start):
+			add("end_date %s is before start_date %s", c.EndDate, c.StartDate)
+		}
+	}
+
+	if len(c.Scrapers) == 0 {
+		add("scrapers must list at least one of %s", strings.Join(storage.DatasetNames(), ", "))
+	}
+	errs = append(errs, validateScrapers("scrapers", c.Scrapers)...)
+
+	if c.Dirs.Data == "" {
+		add("dirs.data must not be empty")
+	}
+
+	oneOf := func(field, value string, allowed []string) {
+		if !slices.Contains(allowed, value) {
+			add("invalid %s %q, must be one of %s", field, value, strings.Join(allowed, ", "))
+		}
+	}
+	oneOf("store", c.Output.Store, Stores)
+	oneOf("format", c.Output.Format, storage.FileFormats)
+	oneOf("combined_format", c.Output.Combined(), storage.CombinedFormats)
+	oneOf("header_lang", c.Output.HeaderLang, storage.HeaderLangs)
+	oneOf("log.level", c.Log.Level, logging.Levels)
+	oneOf("log.format", c.Log.Format, logging.Formats)
+	oneOf("progress", c.Progress, progress.Modes)
+	for i, policy := range c.Order {
+		oneOf("order", policy, Orders)
+		if slices.Index(c.Order, policy) != i {
+			add("order %q listed twice", policy)
+		}
+	}
+	if slices.Contains(c.Order, "priority") && len(c.PriorityGroups) == 0 {
+		add("order priority needs priority_groups")
+	}
+
+	errs = append(errs, c.Daemon.validate()...)
+	errs = append(errs, c.Notify.validate()...)
+	errs = append(errs, c.Archive.validate()...)
+	errs = append(errs, c.ScraperDefaults.validate("scraper_defaults")...)
+	for _, name := range slices.Sorted(maps.Keys(c.ScraperOptions)) {
+		if _, err := storage.LookupDataset(name); err != nil {
+			add("scraper_options: unknown scraper %q", name)
+			continue
+		}
+		errs = append(errs, c.Scraper(name).validate("scraper_options."+name)...)
+	}
+	return errors.Join(errs...)
+}
+
+// validateScrapers checks that the scrapers listed in field exist and are
+// listed once.
+func validateScrapers(field string, scrapers []string) []error {
+	var errs []error
+	for i, name := range scrapers {
+		if _, err := storage.LookupDataset(name); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"%s: unknown scraper %q, must be one of %s",
+				field, name, strings.Join(storage.DatasetNames(), ", "),
+			))
+		} else if slices.Index(scrapers, name) != i {
+			errs = append(errs, fmt.Errorf("%s: scraper %q listed twice", field, name))
+		}
+	}
+	return errs
+}
+
+func (d Daemon) validate() []error {
+	var errs []error
+	if _, err := d.Location(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid daemon.timezone %q: %w", d.Timezone, err))
+	}
+	for i, job := range d.Jobs {
+		field := fmt.Sprintf("daemon.jobs[%d]", i)
+		if job.Name == "" {
+			errs = append(errs, fmt.Errorf("%s.name must not be empty", field))
+		} else if slices.IndexFunc(d.Jobs, func(j Job) bool { return j.Name == job.Name }) != i {
+			errs = append(errs, fmt.Errorf("%s: job %q defined twice", field, job.Name))
+		}
+		if len(job.Schedules) == 0 {
+			errs = append(errs, fmt.Errorf("%s.schedules must list at least one cron expression", field))
+		}
+		for _, expr := range job.Schedules {
+			if _, err := schedule.Parse(expr); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", field, err))
+			}
+		}
+		errs = append(errs, validateScrapers(field+".scrapers", job.Scrapers)...)
+	}
+	return errs
+}
+
+func (a Archive) validate() []error {
+	var errs []error
+	if !slices.Contains(archive.Formats, a.Format) {
+		errs = append(errs, fmt.Errorf(
+			"invalid archive.format %q, must be one of %s", a.Format, strings.Join(archive.Formats, ", "),
+		))
+	}
+	if a.Keep.Daily < 0 || a.Keep.Weekly < 0 || a.Keep.Monthly < 0 {
+		errs = append(errs, fmt.Errorf("archive.keep counts must be >= 0"))
+	}
+	return errs
+}
+
+// Retention returns the archive retention policy.
+func (a Archive) Retention() archive.Retention {
+	return archive.Retention{Daily: a.Keep.Daily, Weekly: a.Keep.Weekly, Monthly: a.Keep.Monthly}
+}
+
+func (n Notify) validate() []error {
+	var errs []error
+	if n.FailureRate < 0 || n.FailureRate > 1 {
+		errs = append(errs, fmt.Errorf("notify.failure_rate must be between 0 and 1, got %g", n.FailureRate))
+	}
+	for i, hook := range n.Webhooks {
+		field := fmt.Sprintf("notify.webhooks[%d]", i)
+		if hook.URL == "" {
+			errs = append(errs, fmt.Errorf("%s.url must not be empty", field))
+		}
+		if !slices.Contains(notify.Formats, hook.Format) {
+			errs = append(errs, fmt.Errorf(
+				"invalid %s.format %q, must be one of %s", field, hook.Format, strings.Join(notify.Formats, ", "),
+			))
+		}
+		if hook.Format == "line" && hook.To == "" {
+			errs = append(errs, fmt.Errorf("%s.to must name the LINE recipient", field))
+		}
+		if _, err := notify.ParseTemplate(hook.Template); err != nil {
+			errs = append(errs, fmt.Errorf("%s.template: %w", field, err))
+		}
+	}
+	return errs
+}
+
+func (o ScraperOptions) validate(field string) []error {
+	var errs []error
+	if o.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("%s.timeout must be > 0", field))
+	}
+	if o.Freshness < 0 {
+		errs = append(errs, fmt.Errorf("%s.freshness must not be negative", field))
+	}
+	if o.Retries < 0 {
+		errs = append(errs, fmt.Errorf("%s.retries must not be negative", field))
+	}
+	return errs
+}
+
+// Scraper returns the effective options of one scraper type: the defaults
+// with that type's overrides applied.
+func (c *Config) Scraper(name string) ScraperOptions {
+	opts := c.ScraperDefaults
+	override, ok := c.ScraperOptions[name]
+	if !ok {
+		return opts
+	}
+	if override.Timeout != nil {
+		opts.Timeout = *override.Timeout
+	}
+	if override.Freshness != nil {
+		opts.Freshness = *override.Freshness
+	}
+	if override.Retries != nil {
+		opts.Retries = *override.Retries
+	}
+	return opts
+}
+
+// DateRange returns the configured dates, or the full history from
+// 1965-01-01 to today when none are set.
+func (c *Config) DateRange() (string, string) {
+	if c.StartDate == "" && c.EndDate == "" {
+		return "1965-01-01", time.Now().Format(dateLayout)
+	}
+	return c.StartDate, c.EndDate
+}
+
+// redacted replaces secrets in Redacted.
+const redacted = "REDACTED"
+
+// Redacted returns a copy of c with the API token, the path and query of
+// webhook URLs and webhook header values replaced, for printing.
+func (c *Config) Redacted() *Config {
+	out := *c
+	if out.Daemon.APIToken != "" {
+		out.Daemon.APIToken = redacted
+	}
+	out.Notify.Webhooks = nil
+	for _, h := range c.Notify.Webhooks {
+		h.URL = notify.RedactURL(h.URL)
+		if len(h.Headers) > 0 {
+			headers := make(map[string]string, len(h.Headers))
+			for name := range h.Headers {
+				headers[name] = redacted
+			}
+			h.Headers = headers
+		}
+		out.Notify.Webhooks = append(out.Notify.Webhooks, h)
+	}
+	return &out
+}
+
+// YAML renders the settings as a config file.
+func (c *Config) YAML() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "scraper.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+workers: 12
+start_date: 2020-01-01
+end_date: 2024-12-31
+scrapers: [per, equity]
+output:
+  format: parquet
+scraper_defaults:
+  retries: 2
+scraper_options:
+  equity:
+    timeout: 45s
+    freshness: 168h
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+
+	if cfg.Workers != 12 || !reflect.DeepEqual(cfg.Scrapers, []string{"per", "equity"}) {
+		t.Fatalf("unexpected workers/scrapers: %d %v", cfg.Workers, cfg.Scrapers)
+	}
+	if cfg.Output.Store != "csv" || cfg.Output.Combined() != "parquet" {
+		t.Fatalf("expected defaults kept and combined format to follow format, got %+v", cfg.Output)
+	}
+	if dirs := cfg.Dirs.Resolve(); dirs.Download != filepath.Join("data", "downloaded_stock") {
+		t.Fatalf("expected default download dir, got %s", dirs.Download)
+	}
+
+	tests := []struct {
+		scraper string
+		want    ScraperOptions
+	}{
+		{"per", ScraperOptions{Timeout: Duration(10 * time.Second), Retries: 2}},
+		{"equity", ScraperOptions{
+			Timeout:   Duration(45 * time.Second),
+			Freshness: Duration(7 * 24 * time.Hour),
+			Retries:   2,
+		}},
+	}
+	for _, tt := range tests {
+		if got := cfg.Scraper(tt.scraper); got != tt.want {
+			t.Errorf("Scraper(%s) = %+v, want %+v", tt.scraper, got, tt.want)
+		}
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, "wokers: 3\n")
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected error for unknown key")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"SCRAPER_WORKERS":              "3",
+		"SCRAPER_SCRAPERS":             "per, stockdata,",
+		"SCRAPER_STORE":                "both",
+		"SCRAPER_FRESHNESS":            "36h",
+		"SCRAPER_DATA_DIR":             "/var/lib/scraper",
+		"SCRAPER_REPORT_HTML":          "true",
+		"SCRAPER_API_ADDR":             ":8080",
+		"SCRAPER_ARCHIVE_FORMAT":       "tar.zst",
+		"SCRAPER_ARCHIVE_AFTER_SCRAPE": "1",
+		"SCRAPER_ARCHIVE_KEEP_WEEKLY":  "8",
+	}
+	cfg := Default()
+	err := cfg.ApplyEnv(func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatalf("ApplyEnv returned error: %v", err)
+	}
+	if cfg.Workers != 3 || cfg.Output.Store != "both" || !cfg.Output.ReportHTML || cfg.Daemon.APIAddr != ":8080" {
+		t.Fatalf("unexpected overrides: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Scrapers, []string{"per", "stockdata"}) {
+		t.Fatalf("unexpected scrapers: %v", cfg.Scrapers)
+	}
+	if want := (Archive{Format: "tar.zst", AfterScrape: true, Keep: Keep{Weekly: 8}}); cfg.Archive != want {
+		t.Fatalf("Archive = %+v, want %+v", cfg.Archive, want)
+	}
+	if cfg.Dirs.Resolve().Failed != filepath.Join("/var/lib/scraper", "failed_stock") {
+		t.Fatalf("expected dirs under SCRAPER_DATA_DIR, got %+v", cfg.Dirs.Resolve())
+	}
+	if cfg.Scraper("per").Freshness != Duration(36*time.Hour) {
+		t.Fatalf("unexpected freshness: %v", cfg.Scraper("per").Freshness)
+	}
+
+	err = cfg.ApplyEnv(func(name string) (string, bool) {
+		if name == "SCRAPER_RETRIES" {
+			return "many", true
+		}
+		return "", false
+	})
+	if err == nil || !strings.Contains(err.Error(), "SCRAPER_RETRIES") {
+		t.Fatalf("expected SCRAPER_RETRIES error, got %v", err)
+	}
+}
+
+func TestDirsResolve(t *testing.T) {
+	root := filepath.Join("srv", "scraper")
+	dirs := Dirs{Data: root, Input: filepath.Join("etc", "stocks")}.Resolve()
+
+	want := Dirs{
+		Data:        root,
+		Input:       filepath.Join("etc", "stocks"),
+		Download:    filepath.Join(root, "downloaded_stock"),
+		FinalOutput: filepath.Join(root, "final_output"),
+		Failed:      filepath.Join(root, "failed_stock"),
+		Export:      filepath.Join(root, "export"),
+		Archive:     filepath.Join(root, "archives"),
+		Report:      filepath.Join(root, "reports"),
+	}
+	if dirs != want {
+		t.Fatalf("Resolve() = %+v, want %+v", dirs, want)
+	}
+
+	cfg := Default()
+	cfg.Dirs.Data = root
+	if got := cfg.DBPath(); got != filepath.Join(root, "scraper.db") {
+		t.Fatalf("DBPath() = %s", got)
+	}
+	cfg.Output.DB = "other.db"
+	if got := cfg.DBPath(); got != "other.db" {
+		t.Fatalf("DBPath() = %s, want explicit db", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr string
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"workers", func(c *Config) { c.Workers = 0 }, "workers must be > 0"},
+		{"half date range", func(c *Config) { c.StartDate = "2020-01-01" }, "must be set together"},
+		{"bad date", func(c *Config) { c.StartDate, c.EndDate = "2020/01/01", "2021-01-01" }, "invalid start_date"},
+		{"reversed dates", func(c *Config) { c.StartDate, c.EndDate = "2021-01-01", "2020-01-01" }, "is before"},
+		{"unknown scraper", func(c *Config) { c.Scrapers = []string{"per", "dividends"} }, `unknown scraper "dividends"`},
+		{"duplicate scraper", func(c *Config) { c.Scrapers = []string{"per", "per"} }, "listed twice"},
+		{"empty data dir", func(c *Config) { c.Dirs.Data = "" }, "dirs.data"},
+		{"store", func(c *Config) { c.Output.Store = "s3" }, "invalid store"},
+		{"combined format", func(c *Config) { c.Output.CombinedFormat = "pdf" }, "invalid combined_format"},
+		{"header lang", func(c *Config) { c.Output.HeaderLang = "jp" }, "invalid header_lang"},
+		{"log level", func(c *Config) { c.Log.Level = "trace" }, "invalid log.level"},
+		{"log format", func(c *Config) { c.Log.Format = "xml" }, "invalid log.format"},
+		{"progress", func(c *Config) { c.Progress = "spinner" }, "invalid progress"},
+		{"order", func(c *Config) { c.Order = []string{"staleness", "alphabetical"} }, `invalid order "alphabetical"`},
+		{"priority without groups", func(c *Config) { c.Order = []string{"priority"} }, "needs priority_groups"},
+		{"timeout", func(c *Config) { c.ScraperDefaults.Timeout = 0 }, "scraper_defaults.timeout"},
+		{"notify", func(c *Config) {
+			c.Notify = Notify{FailureRate: 0.1, Webhooks: []Webhook{
+				{URL: "${SLACK_WEBHOOK_URL}", Format: "slack"},
+				{URL: "https://api.line.me/v2/bot/message/push", Format: "line", To: "U123"},
+			}}
+		}, ""},
+		{"notify failure rate", func(c *Config) { c.Notify.FailureRate = 5 }, "notify.failure_rate"},
+		{"notify format", func(c *Config) {
+			c.Notify.Webhooks = []Webhook{{URL: "http://x", Format: "teams"}}
+		}, "invalid notify.webhooks[0].format"},
+		{"notify line recipient", func(c *Config) {
+			c.Notify.Webhooks = []Webhook{{URL: "http://x", Format: "line"}}
+		}, "LINE recipient"},
+		{"notify template", func(c *Config) {
+			c.Notify.Webhooks = []Webhook{{URL: "http://x", Format: "slack", Template: "{{.Summary"}}
+		}, "notify.webhooks[0].template"},
+		{"archive", func(c *Config) {
+			c.Archive = Archive{Format: "tar.zst", AfterScrape: true, Keep: Keep{Daily: 7, Weekly: 4, Monthly: 12}}
+		}, ""},
+		{"archive format", func(c *Config) { c.Archive.Format = "rar" }, "invalid archive.format"},
+		{"archive keep", func(c *Config) { c.Archive.Keep.Daily = -1 }, "archive.keep"},
+		{"daemon jobs", func(c *Config) {
+			c.Daemon = Daemon{Timezone: "Asia/Taipei", Jobs: []Job{
+				{Name: "prices", Schedules: []string{"0 8 * * sat"}, Scrapers: []string{"stockdata", "per"}},
+				{Name: "cashflow", Schedules: []string{"0 8 16 5,8,11 *", "0 8 1 4 *"}},
+			}}
+		}, ""},
+		{"daemon timezone", func(c *Config) { c.Daemon.Timezone = "Mars/Olympus" }, "invalid daemon.timezone"},
+		{"daemon schedule", func(c *Config) {
+			c.Daemon.Jobs = []Job{{Name: "prices", Schedules: []string{"0 8 * *"}}}
+		}, "want 5 fields"},
+		{"daemon duplicate job", func(c *Config) {
+			c.Daemon.Jobs = []Job{{Name: "a", Schedules: []string{"@daily"}}, {Name: "a", Schedules: []string{"@weekly"}}}
+		}, `job "a" defined twice`},
+		{"daemon job scraper", func(c *Config) {
+			c.Daemon.Jobs = []Job{{Name: "a", Schedules: []string{"@daily"}, Scrapers: []string{"dividends"}}}
+		}, "daemon.jobs[0].scrapers"},
+		{"override retries", func(c *Config) {
+			retries := -1
+			c.ScraperOptions = map[string]ScraperOverride{"per": {Retries: &retries}}
+		}, "scraper_options.per.retries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	cfg := Default()
+	timeout := Duration(time.Minute)
+	cfg.ScraperOptions = map[string]ScraperOverride{"cashflow": {Timeout: &timeout}}
+
+	data, err := cfg.YAML()
+	if err != nil {
+		t.Fatalf("YAML returned error: %v", err)
+	}
+	if !strings.Contains(string(data), "timeout: 1m0s") {
+		t.Fatalf("expected durations rendered as strings, got:\n%s", data)
+	}
+
+	loaded, err := Load(writeConfig(t, string(data)))
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", loaded, cfg)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Daemon.APIToken = "s3cret"
+	cfg.Notify.Webhooks = []Webhook{
+		{URL: "https://example.com/hook/s3cret", Format: "json", Headers: map[string]string{"Authorization": "Bearer s3cret"}},
+	}
+
+	data, err := cfg.Redacted().YAML()
+	if err != nil {
+		t.Fatalf("YAML returned error: %v", err)
+	}
+	if strings.Contains(string(data), "s3cret") {
+		t.Errorf("expected secrets redacted, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "url: https://example.com/REDACTED") {
+		t.Errorf("expected the webhook host kept, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "Authorization: REDACTED") {
+		t.Errorf("expected header names kept, got:\n%s", data)
+	}
+	if cfg.Daemon.APIToken != "s3cret" || cfg.Notify.Webhooks[0].Headers["Authorization"] != "Bearer s3cret" {
+		t.Error("Redacted modified the original config")
+	}
+}
@@ -1,24 +1,27 @@
 package flow
 
 import (
-	"log"
+	"fmt"
 	"os"
 
 	"github.com/playwright-community/playwright-go"
 )
 
-func SetupDirectories(dirs ...string) {
+// SetupDirectories creates every directory in dirs that does not exist yet.
+func SetupDirectories(dirs ...string) error {
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0o755); err != nil {
-			log.Fatalf("Error creating directory '%s': %v", dir, err)
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
 		}
 	}
+	return nil
 }
 
-func SetupPlaywright() *playwright.Playwright {
+// SetupPlaywright starts the Playwright driver; the caller stops it.
+func SetupPlaywright() (*playwright.Playwright, error) {
 	pw, err := playwright.Run()
 	if err != nil {
-		log.Fatalf("Failed to start Playwright: %v", err)
+		return nil, fmt.Errorf("failed to start Playwright: %w", err)
 	}
-	return pw
+	return pw, nil
 }
@@ -0,0 +1,219 @@
+package flow
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+)
+
+// stockIDPattern matches TWSE/TPEx security codes: four digits for common
+// stocks, optionally followed by up to two digits or letters for ETFs,
+// preferred shares, TDRs and the like (0050, 00878, 00679B, 2881A, 910322).
+var stockIDPattern = regexp.MustCompile(`^[0-9]{4}[0-9A-Z]{0,2}$`)
+
+// ValidStockID reports whether id is a TWSE/TPEx security code.
+func ValidStockID(id string) bool {
+	return stockIDPattern.MatchString(id)
+}
+
+// Stock is one entry of the input stock list.
+type Stock struct {
+	ID     string
+	Name   string
+	Groups []string
+}
+
+// InGroup reports whether the stock is tagged with any of groups.
+func (s Stock) InGroup(groups ...string) bool {
+	for _, g := range groups {
+		if slices.Contains(s.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadStockList reads every file in folderPath. Each line holds
+//
+//	id[,name[,groups]]  # optional comment
+//
+// where groups are separated by ";", "," or spaces; a line without commas may
+// also hold several IDs separated by spaces. Blank lines and lines starting
+// with "#" are ignored. A stock listed more than once, in one file or across
+// files, is kept at its first position with its groups merged and the first
+// non-empty name. Every line with an ID that is not a TWSE/TPEx code is
+// reported in the returned error.
+func LoadStockList(folderPath string) ([]Stock, error) {
+	files, err := os.ReadDir(folderPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stock folder: %w", err)
+	}
+
+	list := newStockList()
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		path := filepath.Join(folderPath, file.Name())
+		f, err := os.Open(path)
+		if err != nil {
+			list.errs = append(list.errs, err)
+			continue
+		}
+		list.read(f, path)
+		f.Close()
+	}
+	return list.result()
+}
+
+// ParseStockArgs builds a stock list from command-line arguments, applying
+// the same validation and de-duplication as LoadStockList. Each argument is a
+// stock ID, or "-" to read a stock list in the same format from stdin.
+func ParseStockArgs(args []string, stdin io.Reader) ([]Stock, error) {
+	list := newStockList()
+	for i, arg := range args {
+		if arg == "-" {
+			list.read(stdin, "stdin")
+			continue
+		}
+		for _, stock := range parseStockLine(arg) {
+			list.add(stock, fmt.Sprintf("argument %d", i+1))
+		}
+	}
+	return list.result()
+}
+
+// stockList accumulates validated, de-duplicated stocks from several sources.
+type stockList struct {
+	stocks     []Stock
+	index      map[string]int
+	duplicates int
+	errs       []error
+}
+
+func newStockList() *stockList {
+	return &stockList{index: make(map[string]int)}
+}
+
+// read adds every stock listed in r; source names r in messages.
+func (l *stockList) read(r io.Reader, source string) {
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		for _, stock := range parseStockLine(scanner.Text()) {
+			l.add(stock, fmt.Sprintf("%s:%d", source, lineNo))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s: %w", source, err))
+	}
+}
+
+// add validates stock and appends it, or merges it into an earlier entry
+// with the same ID.
+func (l *stockList) add(stock Stock, source string) {
+	if !ValidStockID(stock.ID) {
+		l.errs = append(l.errs, fmt.Errorf("%s: invalid stock id %q", source, stock.ID))
+		return
+	}
+	i, seen := l.index[stock.ID]
+	if !seen {
+		l.index[stock.ID] = len(l.stocks)
+		l.stocks = append(l.stocks, stock)
+		return
+	}
+	slog.Debug("Duplicate stock; merging.", logging.KeyStock, stock.ID, "source", source)
+	l.duplicates++
+	if l.stocks[i].Name == "" {
+		l.stocks[i].Name = stock.Name
+	}
+	for _, g := range stock.Groups {
+		if !slices.Contains(l.stocks[i].Groups, g) {
+			l.stocks[i].Groups = append(l.stocks[i].Groups, g)
+		}
+	}
+}
+
+func (l *stockList) result() ([]Stock, error) {
+	if len(l.errs) > 0 {
+		return nil, errors.Join(l.errs...)
+	}
+	if l.duplicates > 0 {
+		slog.Info("Merged duplicate stocks.", "duplicates", l.duplicates, "stocks", len(l.stocks))
+	}
+	return l.stocks, nil
+}
+
+// parseStockLine parses one line of a stock list, returning nothing for blank
+// and comment lines.
+func parseStockLine(line string) []Stock {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+	if !strings.Contains(line, ",") {
+		var stocks []Stock
+		for _, id := range strings.Fields(line) {
+			stocks = append(stocks, Stock{ID: strings.ToUpper(id)})
+		}
+		return stocks
+	}
+
+	parts := strings.SplitN(line, ",", 3)
+	stock := Stock{ID: strings.ToUpper(strings.TrimSpace(parts[0]))}
+	if len(parts) > 1 {
+		stock.Name = strings.TrimSpace(parts[1])
+	}
+	if len(parts) > 2 {
+		stock.Groups = strings.FieldsFunc(parts[2], func(r rune) bool {
+			return r == ';' || r == ',' || r == ' ' || r == '\t'
+		})
+	}
+	return []Stock{stock}
+}
+
+// FilterStocks returns the IDs of the stocks tagged with any of groups, or of
+// every stock when no groups are given.
+func FilterStocks(stocks []Stock, groups ...string) []string {
+	var ids []string
+	for _, s := range stocks {
+		if len(groups) == 0 || s.InGroup(groups...) {
+			ids = append(ids, s.ID)
+		}
+	}
+	return ids
+}
+
+// GetStockNumbers returns the IDs in the stock list in folderPath, limited to
+// the stocks tagged with any of groups when groups are given. It fails when
+// no stock is left.
+func GetStockNumbers(folderPath string, groups ...string) ([]string, error) {
+	stocks, err := LoadStockList(folderPath)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := FilterStocks(stocks, groups...)
+	if len(ids) == 0 {
+		if len(groups) > 0 {
+			return nil, fmt.Errorf(
+				"no stocks in group(s) %s in %s",
+				strings.Join(groups, ", "),
+				folderPath,
+			)
+		}
+		return nil, fmt.Errorf("no stock numbers found in %s", folderPath)
+	}
+	return ids, nil
+}
@@ -0,0 +1,129 @@
+package flow
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeStockFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+func TestLoadStockList(t *testing.T) {
+	dir := writeStockFiles(t, map[string]string{
+		"a.txt": `# id,name,groups
+2330,台積電,semis;watchlist
+2317
+0050, 元大台灣50 ,etf   # benchmark
+
+00679b,,etf bonds
+`,
+		"b.txt": `2330,,core  # listed again
+2454,聯發科,semis
+`,
+	})
+
+	stocks, err := LoadStockList(dir)
+	if err != nil {
+		t.Fatalf("LoadStockList returned error: %v", err)
+	}
+	want := []Stock{
+		{ID: "2330", Name: "台積電", Groups: []string{"semis", "watchlist", "core"}},
+		{ID: "2317"},
+		{ID: "0050", Name: "元大台灣50", Groups: []string{"etf"}},
+		{ID: "00679B", Groups: []string{"etf", "bonds"}},
+		{ID: "2454", Name: "聯發科", Groups: []string{"semis"}},
+	}
+	if !reflect.DeepEqual(stocks, want) {
+		t.Fatalf("LoadStockList() =\n%+v\nwant\n%+v", stocks, want)
+	}
+}
+
+func TestLoadStockListInvalidIDs(t *testing.T) {
+	dir := writeStockFiles(t, map[string]string{
+		"stocks.txt": "2330\nTSMC\n123\n2330.TW\n",
+	})
+	_, err := LoadStockList(dir)
+	if err == nil {
+		t.Fatalf("expected error for invalid ids")
+	}
+	for _, id := range []string{`"TSMC"`, `"123"`, `"2330.TW"`} {
+		if !strings.Contains(err.Error(), id) {
+			t.Errorf("expected error to mention %s, got %v", id, err)
+		}
+	}
+	if !strings.Contains(err.Error(), "stocks.txt:3") {
+		t.Errorf("expected error to carry file and line, got %v", err)
+	}
+}
+
+func TestGetStockNumbers(t *testing.T) {
+	dir := writeStockFiles(t, map[string]string{
+		"stocks.txt": "2330,TSMC,semis\n2317,Hon Hai,ems\n2454,MediaTek,semis watchlist\n",
+	})
+
+	tests := []struct {
+		name    string
+		groups  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", want: []string{"2330", "2317", "2454"}},
+		{name: "one group", groups: []string{"semis"}, want: []string{"2330", "2454"}},
+		{name: "any group", groups: []string{"ems", "watchlist"}, want: []string{"2317", "2454"}},
+		{name: "unknown group", groups: []string{"banks"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetStockNumbers(dir, tt.groups...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetStockNumbers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("GetStockNumbers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := GetStockNumbers(t.TempDir()); err == nil {
+		t.Fatalf("expected error for an empty folder")
+	}
+}
+
+func TestParseStockArgs(t *testing.T) {
+	stdin := strings.NewReader("# from another tool\n2317 2454\n2330,TSMC,semis\n")
+
+	stocks, err := ParseStockArgs([]string{"2330", "-", "0050"}, stdin)
+	if err != nil {
+		t.Fatalf("ParseStockArgs returned error: %v", err)
+	}
+	want := []Stock{
+		{ID: "2330", Name: "TSMC", Groups: []string{"semis"}},
+		{ID: "2317"},
+		{ID: "2454"},
+		{ID: "0050"},
+	}
+	if !reflect.DeepEqual(stocks, want) {
+		t.Fatalf("ParseStockArgs() =\n%+v\nwant\n%+v", stocks, want)
+	}
+
+	_, err = ParseStockArgs([]string{"2330", "abc", "-"}, strings.NewReader("99\n"))
+	if err == nil {
+		t.Fatalf("expected error for invalid ids")
+	}
+	for _, source := range []string{`argument 2: invalid stock id "ABC"`, `stdin:1: invalid stock id "99"`} {
+		if !strings.Contains(err.Error(), source) {
+			t.Errorf("expected error to contain %q, got %v", source, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package lock
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// errHeld is returned by tryLock when another open file holds the lock.
+var errHeld = errors.New("lock held")
+
+// lockBytes is the range locked, past the recorded owner so it stays
+// readable while the lock is held.
+const lockBytes = 1
+
+// tryLock takes an exclusive lock on file without waiting.
+func tryLock(file *os.File) error {
+	err := windows.LockFileEx(
+		windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0,
+		lockBytes,
+		0,
+		&windows.Overlapped{OffsetHigh: 1},
+	)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errHeld
+	}
+	return err
+}
+
+func unlock(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, lockBytes, 0, &windows.Overlapped{OffsetHigh: 1})
+}
@@ -0,0 +1,83 @@
+// Package lock keeps two scraper processes from running at the same time
+// with an advisory lock on a lock file, which also records the owner's
+// process ID.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is returned by Acquire while another live process holds the lock.
+var ErrLocked = errors.New("locked by another run")
+
+// Lock is a held lock file.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire locks the file at path, creating it when missing. The operating
+// system drops the lock when its owner exits, so a lock file left behind by
+// a killed process is simply locked again; one held by a live process fails
+// with an error wrapping ErrLocked.
+func Acquire(path string) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock %s: %w", path, err)
+	}
+	if err := tryLock(file); err != nil {
+		file.Close()
+		if !errors.Is(err, errHeld) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if pid, since, ok := owner(path); ok {
+			return nil, fmt.Errorf("%s: %w (pid %d since %s)", path, ErrLocked, pid, since)
+		}
+		return nil, fmt.Errorf("%s: %w", path, ErrLocked)
+	}
+
+	err = file.Truncate(0)
+	if err == nil {
+		_, err = fmt.Fprintf(file, "%d\n%s\n", os.Getpid(), time.Now().Format(time.RFC3339))
+	}
+	if err != nil {
+		unlock(file)
+		file.Close()
+		return nil, fmt.Errorf("failed to write lock %s: %w", path, err)
+	}
+	return &Lock{file: file}, nil
+}
+
+// owner reads the process ID and start time from the lock file.
+func owner(path string) (pid int, since string, ok bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, "", false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, "", false
+	}
+	pid, err = strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", false
+	}
+	if len(fields) > 1 {
+		since = fields[1]
+	}
+	return pid, since, true
+}
+
+// Release unlocks the lock file. The file itself stays, so that a process
+// waiting to open it never locks a file that is about to be removed.
+func (l *Lock) Release() error {
+	return errors.Join(unlock(l.file), l.file.Close())
+}
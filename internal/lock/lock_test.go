@@ -0,0 +1,83 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "scraper.lock")
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire returned error: %v", err)
+	}
+	if _, err := Acquire(path); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second Acquire error = %v, want ErrLocked", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release returned error: %v", err)
+	}
+	l, err = Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire after Release returned error: %v", err)
+	}
+	l.Release()
+}
+
+func TestAcquireTakesOverStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scraper.lock")
+	// No process can have a PID this large on Linux or macOS.
+	if err := os.WriteFile(path, []byte("2147483646\n2024-01-01T00:00:00Z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire returned error: %v", err)
+	}
+	defer l.Release()
+}
+
+func TestAcquireOwnPIDIsNotHeld(t *testing.T) {
+	// A container restarted after being killed runs as the same PID as the
+	// process that left the lock file behind.
+	path := filepath.Join(t.TempDir(), "scraper.lock")
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d\n2024-01-01T00:00:00Z\n", os.Getpid())), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := Acquire(path)
+	if err != nil {
+		t.Fatalf("Acquire returned error: %v", err)
+	}
+	defer l.Release()
+	if pid, _, ok := owner(path); !ok || pid != os.Getpid() {
+		t.Errorf("owner() = %d, %v, want this process", pid, ok)
+	}
+}
+
+func TestAcquireConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scraper.lock")
+	const n = 8
+	locks := make(chan *Lock, n)
+	for range n {
+		go func() {
+			l, err := Acquire(path)
+			if err != nil && !errors.Is(err, ErrLocked) {
+				t.Errorf("Acquire returned error: %v", err)
+			}
+			locks <- l
+		}()
+	}
+	held := 0
+	for range n {
+		if l := <-locks; l != nil {
+			held++
+			defer l.Release()
+		}
+	}
+	if held != 1 {
+		t.Errorf("%d concurrent Acquire calls succeeded, want 1", held)
+	}
+}
@@ -0,0 +1,90 @@
+// Package logging configures the process-wide structured logger.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Levels lists the accepted log levels.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// Formats lists the accepted log formats.
+var Formats = []string{"text", "json"}
+
+// Field names shared by every log record about a scrape task, so records can
+// be filtered the same way whichever package wrote them.
+const (
+	KeyStock     = "stock"
+	KeyScraper   = "scraper"
+	KeyAttempt   = "attempt"
+	KeyDuration  = "duration"
+	KeyURL       = "url"
+	KeyErrorKind = "error_kind"
+	KeyError     = "error"
+)
+
+// ParseLevel converts one of Levels into a slog.Level.
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if !slices.Contains(Levels, level) {
+		return l, fmt.Errorf("invalid log level %q, must be one of %s", level, strings.Join(Levels, ", "))
+	}
+	err := l.UnmarshalText([]byte(level))
+	return l, err
+}
+
+// New returns a logger writing records at level or above to w in format.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: l}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, must be one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Setup installs the default logger, which also receives the output of the
+// standard log package. Records go to stderr, the writer standing for the
+// process's standard error, or are appended to file when it is set. The
+// returned function closes the log file.
+func Setup(level, format, file string, stderr io.Writer) (func() error, error) {
+	var (
+		w     = stderr
+		close = func() error { return nil }
+	)
+	if file != "" {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w, close = f, f.Close
+	}
+
+	logger, err := New(w, level, format)
+	if err != nil {
+		close()
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	// slog.SetDefault redirects the log package at the info level; keep its
+	// records free of the log package's own timestamp.
+	log.SetFlags(0)
+	return close, nil
+}
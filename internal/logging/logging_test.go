@@ -0,0 +1,87 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{
+			name:    "text info",
+			level:   "info",
+			format:  "text",
+			want:    []string{`level=INFO msg=Scraped stock=2330 scraper=per`},
+			notWant: []string{"debug detail"},
+		},
+		{
+			name:   "json debug",
+			level:  "debug",
+			format: "json",
+			want:   []string{`"stock":"2330"`, `"msg":"debug detail"`},
+		},
+		{name: "bad level", level: "verbose", format: "text", wantErr: true},
+		{name: "bad format", level: "info", format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, tt.level, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			logger.Debug("debug detail", KeyStock, "2330")
+			logger.Info("Scraped", KeyStock, "2330", KeyScraper, "per")
+			for _, s := range tt.want {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("expected output to contain %q, got:\n%s", s, buf.String())
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(buf.String(), s) {
+					t.Errorf("expected output not to contain %q, got:\n%s", s, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestSetupLogFile(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	path := t.TempDir() + "/logs/scraper.log"
+	closeLog, err := Setup("info", "json", path, os.Stderr)
+	if err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+	slog.Warn("Save failed", KeyStock, "2330", KeyErrorKind, "save")
+	if err := closeLog(); err != nil {
+		t.Fatalf("failed to close log: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal(bytes.TrimSpace(data), &rec); err != nil {
+		t.Fatalf("expected one JSON record, got %q: %v", data, err)
+	}
+	if rec["level"] != "WARN" || rec[KeyStock] != "2330" || rec[KeyErrorKind] != "save" {
+		t.Fatalf("unexpected record: %v", rec)
+	}
+}
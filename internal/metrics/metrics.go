@@ -0,0 +1,116 @@
+// Package metrics exposes the scraper's Prometheus metrics. The collectors
+// are package variables updated by the scraper as it runs; Serve publishes
+// them on /metrics.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+)
+
+// Registry holds every collector of this package plus the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// Tasks counts finished scrape tasks by scraper type and outcome
+	// (completed, failed or skipped).
+	Tasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_tasks_total",
+		Help: "Scrape tasks finished, by scraper type and outcome.",
+	}, []string{"scraper", "outcome"})
+
+	// Retries counts retried scrape attempts by scraper type.
+	Retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "scraper_retries_total",
+		Help: "Scrape attempts retried after a failure, by scraper type.",
+	}, []string{"scraper"})
+
+	// FetchDuration observes page fetches by result: ok or the error kind.
+	FetchDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "scraper_fetch_duration_seconds",
+		Help:    "Time to create a browser context, load a page and read its data table, by result.",
+		Buckets: []float64{0.5, 1, 2, 3, 5, 8, 13, 20, 30, 60},
+	}, []string{"result"})
+
+	// BrowserLaunchFailures counts browsers that failed to start.
+	BrowserLaunchFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "scraper_browser_launch_failures_total",
+		Help: "Browser launches that failed.",
+	})
+
+	// Blocks counts pages on which the site refused to serve the data.
+	Blocks = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "scraper_blocks_total",
+		Help: "Page loads the site answered with a block or rate-limit response.",
+	})
+
+	// ActiveWorkers is the number of workers scraping right now.
+	ActiveWorkers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "scraper_active_workers",
+		Help: "Workers currently scraping a task.",
+	})
+
+	// QueueDepth is the number of tasks of the current run not yet started.
+	QueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "scraper_queue_depth",
+		Help: "Scrape tasks of the current run waiting for a worker.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		Tasks,
+		Retries,
+		FetchDuration,
+		BrowserLaunchFailures,
+		Blocks,
+		ActiveWorkers,
+		QueueDepth,
+	)
+}
+
+// Handler serves the metrics of Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// shutdownTimeout bounds how long stopping the server waits for a scrape of
+// /metrics in progress.
+const shutdownTimeout = 5 * time.Second
+
+// Serve serves Handler on /metrics at addr in the background. The returned
+// function stops the server.
+func Serve(addr string) (stop func(), err error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("Metrics server stopped.", logging.KeyError, err)
+		}
+	}()
+	slog.Info("Serving metrics.", "addr", listener.Addr().String())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		srv.Shutdown(ctx)
+	}, nil
+}
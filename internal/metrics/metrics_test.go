@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	Tasks.WithLabelValues("per", "completed").Inc()
+	Retries.WithLabelValues("cashflow").Inc()
+	FetchDuration.WithLabelValues("timeout").Observe(12)
+	QueueDepth.Set(7)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{
+		`scraper_tasks_total{outcome="completed",scraper="per"} 1`,
+		`scraper_retries_total{scraper="cashflow"} 1`,
+		`scraper_fetch_duration_seconds_bucket{result="timeout",le="13"} 1`,
+		`scraper_browser_launch_failures_total 0`,
+		`scraper_blocks_total 0`,
+		`scraper_active_workers 0`,
+		`scraper_queue_depth 7`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Package notify posts the outcome of scrape runs to webhooks: a generic
+// JSON document, or a chat message in the payload format of Slack, Discord
+// or the LINE Messaging API.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/report"
+)
+
+// Formats lists the accepted Webhook formats.
+var Formats = []string{"json", "slack", "discord", "line"}
+
+// Event reasons.
+const (
+	ReasonFinished    = "finished"     // every run is reported
+	ReasonFailureRate = "failure_rate" // the failure rate exceeded the threshold
+)
+
+// maxFailedListed bounds the failed stocks named in a chat message.
+const maxFailedListed = 20
+
+// messageLimits are the longest texts the chat services accept.
+var messageLimits = map[string]int{"discord": 2000, "line": 5000}
+
+// DefaultTemplate is the text/template of chat messages; it is executed
+// with the Event.
+const DefaultTemplate = `{{if eq .Reason "failure_rate"}}:warning: {{end}}Scrape run {{if .Interrupted}}interrupted{{else}}finished{{end}}: ` +
+	`{{.Summary.Succeeded}}/{{.Stocks}} stocks succeeded, {{.Summary.Failed}} failed ({{percent .FailureRate}}) in {{.Duration}}.` +
+	`{{if .FailedStocks}}
+Failed: {{list .FailedStocks}}{{end}}{{if .Report}}
+Report: {{.Report}}{{end}}`
+
+// Event is the outcome of one run as sent to webhooks.
+type Event struct {
+	Reason      string    `json:"reason"`
+	Interrupted bool      `json:"interrupted"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	// Duration is Seconds rounded to the second, for messages.
+	Duration     time.Duration  `json:"-"`
+	Seconds      report.Seconds `json:"duration_seconds"`
+	Stocks       int            `json:"stocks"`
+	Summary      report.Summary `json:"summary"`
+	FailureRate  float64        `json:"failure_rate"`
+	FailedStocks []string       `json:"failed_stocks"`
+	// Report is the path of the JSON run report.
+	Report string `json:"report,omitempty"`
+}
+
+// NewEvent describes the finished run rep, whose JSON report was written to
+// reportPath.
+func NewEvent(rep *report.Report, reportPath string) Event {
+	e := Event{
+		Reason:       ReasonFinished,
+		StartedAt:    rep.StartedAt,
+		FinishedAt:   rep.FinishedAt,
+		Duration:     time.Duration(rep.Duration).Round(time.Second),
+		Seconds:      rep.Duration,
+		Stocks:       rep.Parameters.Stocks,
+		Summary:      rep.Summary,
+		FailedStocks: []string{},
+		Report:       reportPath,
+	}
+	if e.Stocks > 0 {
+		e.FailureRate = float64(rep.Summary.Failed) / float64(e.Stocks)
+	}
+	for _, st := range rep.Stocks {
+		if st.Status == report.StatusFailed {
+			e.FailedStocks = append(e.FailedStocks, st.ID)
+		}
+	}
+	return e
+}
+
+// Webhook is one notification target.
+type Webhook struct {
+	// URL and the Headers values may reference environment variables as
+	// $NAME or ${NAME}, keeping secrets out of config files.
+	URL     string
+	Headers map[string]string
+	// Format is one of Formats.
+	Format string
+	// To is the recipient of LINE push messages.
+	To string
+	// Template replaces DefaultTemplate for chat formats.
+	Template string
+}
+
+// Options configures a Notifier.
+type Options struct {
+	// Always reports every run; otherwise only runs whose failure rate
+	// exceeds FailureRate are reported.
+	Always bool
+	// FailureRate is the fraction of stocks, from 0 to 1, above which a run
+	// is reported as failing.
+	FailureRate float64
+	Webhooks    []Webhook
+	// Client sends the requests; nil means a client with a 10s timeout.
+	Client *http.Client
+}
+
+// Notifier sends events to webhooks.
+type Notifier struct {
+	opts      Options
+	templates []*template.Template
+}
+
+// funcs are the functions available to message templates.
+var funcs = template.FuncMap{
+	"percent": func(f float64) string { return fmt.Sprintf("%.1f%%", f*100) },
+	"list": func(stocks []string) string {
+		if len(stocks) > maxFailedListed {
+			return fmt.Sprintf("%s and %d more", strings.Join(stocks[:maxFailedListed], ", "), len(stocks)-maxFailedListed)
+		}
+		return strings.Join(stocks, ", ")
+	},
+}
+
+// ParseTemplate parses a message template, DefaultTemplate when text is
+// empty.
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	return template.New("message").Funcs(funcs).Parse(text)
+}
+
+// New returns a Notifier, failing on an unknown format or a template that
+// does not parse.
+func New(opts Options) (*Notifier, error) {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	n := &Notifier{opts: opts}
+	for i, hook := range opts.Webhooks {
+		if !slices.Contains(Formats, hook.Format) {
+			return nil, fmt.Errorf("webhook %d: unknown format %q", i+1, hook.Format)
+		}
+		tmpl, err := ParseTemplate(hook.Template)
+		if err != nil {
+			return nil, fmt.Errorf("webhook %d: %w", i+1, err)
+		}
+		n.templates = append(n.templates, tmpl)
+	}
+	return n, nil
+}
+
+// Notify sends e to every webhook when the options call for it: always, or
+// when its failure rate exceeds the threshold, which then becomes its
+// reason. It reports whether e was sent, and the errors of the webhooks
+// that failed.
+func (n *Notifier) Notify(ctx context.Context, e Event) (bool, error) {
+	if n == nil || len(n.opts.Webhooks) == 0 {
+		return false, nil
+	}
+	if e.FailureRate > n.opts.FailureRate && e.Summary.Failed > 0 {
+		e.Reason = ReasonFailureRate
+	} else if !n.opts.Always {
+		return false, nil
+	}
+
+	var errs []error
+	for i, hook := range n.opts.Webhooks {
+		if err := n.send(ctx, hook, n.templates[i], e); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %d (%s): %w", i+1, hook.Format, err))
+		}
+	}
+	return true, errors.Join(errs...)
+}
+
+// send posts e to hook.
+func (n *Notifier) send(ctx context.Context, hook Webhook, tmpl *template.Template, e Event) error {
+	body, err := Payload(hook, tmpl, e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(hook.URL), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range hook.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	resp, err := n.opts.Client.Do(req)
+	if err != nil {
+		// The error names the URL, whose path is the secret of most webhooks.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = RedactURL(urlErr.URL)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// RedactURL returns the scheme and host of a webhook URL, hiding the path,
+// query and credentials that Slack and Discord webhooks carry their secret
+// in. A URL that is only an environment variable reference is kept as is.
+func RedactURL(raw string) string {
+	const redacted = "REDACTED"
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		if strings.HasPrefix(raw, "$") && !strings.ContainsAny(raw, "/?") {
+			return raw
+		}
+		return redacted
+	}
+	if u.Path == "" && u.RawQuery == "" && u.User == nil {
+		return u.Scheme + "://" + u.Host
+	}
+	return u.Scheme + "://" + u.Host + "/" + redacted
+}
+
+// Payload returns the request body of e in hook's format; chat formats
+// carry the message rendered by tmpl.
+func Payload(hook Webhook, tmpl *template.Template, e Event) ([]byte, error) {
+	if hook.Format == "json" {
+		return json.Marshal(e)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, e); err != nil {
+		return nil, err
+	}
+	text := buf.String()
+	if limit, ok := messageLimits[hook.Format]; ok && len([]rune(text)) > limit {
+		text = string([]rune(text)[:limit-1]) + "…"
+	}
+
+	switch hook.Format {
+	case "slack":
+		return json.Marshal(map[string]string{"text": text})
+	case "discord":
+		return json.Marshal(map[string]string{"content": text})
+	default: // line
+		type message struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		return json.Marshal(struct {
+			To       string    `json:"to"`
+			Messages []message `json:"messages"`
+		}{hook.To, []message{{"text", text}}})
+	}
+}
@@ -0,0 +1,308 @@
+// Package progress tracks a scrape run and reports its progress, as a live
+// bar on terminals and as periodic log lines otherwise.
+package progress
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Modes lists the accepted display modes. auto picks bar on a terminal and
+// lines otherwise.
+var Modes = []string{"auto", "bar", "lines", "off"}
+
+// Outcome is how one scrape task ended.
+type Outcome int
+
+const (
+	Completed Outcome = iota
+	Failed
+	Skipped // already up to date
+)
+
+// String returns "completed", "failed" or "skipped".
+func (o Outcome) String() string {
+	switch o {
+	case Completed:
+		return "completed"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	}
+	return "unknown"
+}
+
+// Counts tallies the tasks of a run or of one scraper type.
+type Counts struct {
+	Total     int
+	Completed int
+	Failed    int
+	Skipped   int
+}
+
+// Done returns the number of finished tasks.
+func (c Counts) Done() int {
+	return c.Completed + c.Failed + c.Skipped
+}
+
+func (c *Counts) add(o Outcome) {
+	switch o {
+	case Completed:
+		c.Completed++
+	case Failed:
+		c.Failed++
+	case Skipped:
+		c.Skipped++
+	}
+}
+
+// ScraperCounts are the counts of one scraper type.
+type ScraperCounts struct {
+	Scraper string
+	Counts
+}
+
+// Snapshot is the state of a run at one moment.
+type Snapshot struct {
+	Counts
+	Scrapers []ScraperCounts
+	Elapsed  time.Duration
+	// Rate is the number of tasks scraped (completed or failed) per second;
+	// skipped tasks take no time and would inflate it.
+	Rate float64
+	// ETA estimates the time left from Rate; zero when unknown or done.
+	ETA time.Duration
+}
+
+// Tracker counts the outcomes of the tasks of a run. It is safe for
+// concurrent use, and a nil *Tracker ignores every call.
+type Tracker struct {
+	mu       sync.Mutex
+	start    time.Time
+	now      func() time.Time
+	scrapers []string
+	counts   map[string]*Counts
+}
+
+// NewTracker returns a tracker for a run of every scraper type over stocks
+// stocks.
+func NewTracker(stocks int, scrapers []string) *Tracker {
+	t := &Tracker{
+		now:      time.Now,
+		scrapers: scrapers,
+		counts:   make(map[string]*Counts, len(scrapers)),
+	}
+	for _, s := range scrapers {
+		t.counts[s] = &Counts{Total: stocks}
+	}
+	t.start = t.now()
+	return t
+}
+
+// Record counts the outcome of one task of scraper.
+func (t *Tracker) Record(scraper string, o Outcome) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok := t.counts[scraper]; ok {
+		c.add(o)
+	}
+}
+
+// Snapshot returns the current counts, throughput and ETA.
+func (t *Tracker) Snapshot() Snapshot {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := Snapshot{Elapsed: t.now().Sub(t.start)}
+	for _, name := range t.scrapers {
+		c := *t.counts[name]
+		s.Scrapers = append(s.Scrapers, ScraperCounts{name, c})
+		s.Total += c.Total
+		s.Completed += c.Completed
+		s.Failed += c.Failed
+		s.Skipped += c.Skipped
+	}
+	if scraped := s.Completed + s.Failed; scraped > 0 && s.Elapsed > 0 {
+		s.Rate = float64(scraped) / s.Elapsed.Seconds()
+		remaining := s.Total - s.Done()
+		s.ETA = time.Duration(float64(remaining) / s.Rate * float64(time.Second)).Round(time.Second)
+	}
+	return s
+}
+
+// barWidth is the number of cells of the progress bar.
+const barWidth = 30
+
+// Bar renders s as a one-line progress bar.
+func Bar(s Snapshot) string {
+	filled := 0
+	if s.Total > 0 {
+		filled = s.Done() * barWidth / s.Total
+	}
+	var b strings.Builder
+	fmt.Fprintf(
+		&b,
+		"[%s%s] %s | ok %d fail %d skip %d | %.1f/s",
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", barWidth-filled),
+		fraction(s.Counts),
+		s.Completed,
+		s.Failed,
+		s.Skipped,
+		s.Rate,
+	)
+	if s.ETA > 0 {
+		fmt.Fprintf(&b, " ETA %s", s.ETA)
+	}
+	b.WriteString(" |")
+	for _, sc := range s.Scrapers {
+		fmt.Fprintf(&b, " %s %d/%d", sc.Scraper, sc.Done(), sc.Total)
+		if sc.Failed > 0 {
+			fmt.Fprintf(&b, " (%d failed)", sc.Failed)
+		}
+	}
+	return b.String()
+}
+
+func fraction(c Counts) string {
+	pct := 100.0
+	if c.Total > 0 {
+		pct = float64(c.Done()) * 100 / float64(c.Total)
+	}
+	return fmt.Sprintf("%d/%d %.0f%%", c.Done(), c.Total, pct)
+}
+
+// Log writes s as one structured log record.
+func Log(logger *slog.Logger, msg string, s Snapshot) {
+	per := make([]string, len(s.Scrapers))
+	for i, sc := range s.Scrapers {
+		per[i] = fmt.Sprintf("%s=%d/%d/%d/%d", sc.Scraper, sc.Completed, sc.Failed, sc.Skipped, sc.Total)
+	}
+	logger.Info(
+		msg,
+		"done", s.Done(),
+		"total", s.Total,
+		"completed", s.Completed,
+		"failed", s.Failed,
+		"skipped", s.Skipped,
+		"rate", fmt.Sprintf("%.2f/s", s.Rate),
+		"eta", s.ETA,
+		"elapsed", s.Elapsed.Round(time.Second),
+		// completed/failed/skipped/total per scraper type
+		"by_scraper", strings.Join(per, " "),
+	)
+}
+
+// IsTerminal reports whether f is a terminal rather than a file or pipe.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Console is a terminal shared by the progress bar and the log. While a bar
+// is drawn, every write clears the bar's line, writes, and draws the bar
+// again below, so log lines never end up inside the bar.
+type Console struct {
+	f *os.File
+
+	mu  sync.Mutex
+	bar string // the bar on screen, if any
+}
+
+// NewConsole returns a Console writing to f.
+func NewConsole(f *os.File) *Console {
+	return &Console{f: f}
+}
+
+// Write writes p, which should hold whole lines, above the bar.
+func (c *Console) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.bar == "" {
+		return c.f.Write(p)
+	}
+	fmt.Fprint(c.f, "\r\033[K")
+	n, err := c.f.Write(p)
+	fmt.Fprint(c.f, c.bar)
+	return n, err
+}
+
+// drawBar redraws the bar over the current terminal line, ending the line
+// once the run is over.
+func (c *Console) drawBar(s Snapshot, final bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bar = Bar(s)
+	fmt.Fprintf(c.f, "\r\033[K%s", c.bar)
+	if final {
+		fmt.Fprintln(c.f)
+		c.bar = ""
+	}
+}
+
+// Intervals between two reports of each mode.
+const (
+	BarInterval   = 250 * time.Millisecond
+	LinesInterval = 30 * time.Second
+)
+
+// Start reports the progress of t in mode until the returned function is
+// called, which reports the final state. The bar is drawn on c when it is a
+// terminal, and log records written through c meanwhile appear above it;
+// lines are written to the default logger.
+func Start(t *Tracker, mode string, c *Console) (stop func()) {
+	if mode == "auto" {
+		mode = "lines"
+		if IsTerminal(c.f) {
+			mode = "bar"
+		}
+	}
+
+	var report func(final bool)
+	interval := LinesInterval
+	switch mode {
+	case "bar":
+		interval = BarInterval
+		report = func(final bool) { c.drawBar(t.Snapshot(), final) }
+	case "lines":
+		report = func(final bool) {
+			msg := "Progress."
+			if final {
+				msg = "Progress complete."
+			}
+			Log(slog.Default(), msg, t.Snapshot())
+		}
+	default:
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report(false)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+		report(true)
+	}
+}
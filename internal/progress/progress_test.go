@@ -0,0 +1,119 @@
+package progress
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestTracker(stocks int, scrapers ...string) (*Tracker, *time.Time) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	t := NewTracker(stocks, scrapers)
+	t.start, t.now = now, func() time.Time { return now }
+	return t, &now
+}
+
+func TestTrackerSnapshot(t *testing.T) {
+	tracker, now := newTestTracker(10, "per", "cashflow")
+
+	var wg sync.WaitGroup
+	record := func(scraper string, o Outcome, n int) {
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				tracker.Record(scraper, o)
+			}()
+		}
+	}
+	record("per", Completed, 4)
+	record("per", Skipped, 2)
+	record("cashflow", Completed, 3)
+	record("cashflow", Failed, 1)
+	record("dividends", Completed, 1) // not part of the run
+	wg.Wait()
+	*now = now.Add(4 * time.Second)
+
+	s := tracker.Snapshot()
+	want := Counts{Total: 20, Completed: 7, Failed: 1, Skipped: 2}
+	if s.Counts != want {
+		t.Fatalf("Snapshot().Counts = %+v, want %+v", s.Counts, want)
+	}
+	if s.Scrapers[1] != (ScraperCounts{"cashflow", Counts{Total: 10, Completed: 3, Failed: 1}}) {
+		t.Fatalf("unexpected cashflow counts: %+v", s.Scrapers[1])
+	}
+	// 8 tasks scraped in 4s, 10 left.
+	if s.Rate != 2 || s.ETA != 5*time.Second {
+		t.Fatalf("Rate = %v, ETA = %v, want 2/s and 5s", s.Rate, s.ETA)
+	}
+
+	var nilTracker *Tracker
+	nilTracker.Record("per", Completed)
+}
+
+func TestBar(t *testing.T) {
+	s := Snapshot{
+		Counts: Counts{Total: 20, Completed: 7, Failed: 1, Skipped: 2},
+		Scrapers: []ScraperCounts{
+			{"per", Counts{Total: 10, Completed: 4, Skipped: 2}},
+			{"cashflow", Counts{Total: 10, Completed: 3, Failed: 1}},
+		},
+		Rate: 2,
+		ETA:  5 * time.Second,
+	}
+	got := Bar(s)
+	want := "[" + strings.Repeat("=", 15) + strings.Repeat(" ", 15) + "] 10/20 50% | ok 7 fail 1 skip 2 | 2.0/s ETA 5s" +
+		" | per 6/10 cashflow 4/10 (1 failed)"
+	if got != want {
+		t.Fatalf("Bar() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestConsoleWritesAboveBar(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	c := NewConsole(f)
+	s := Snapshot{Counts: Counts{Total: 2, Completed: 1}}
+
+	c.Write([]byte("before\n"))
+	c.drawBar(s, false)
+	c.Write([]byte("during\n"))
+	c.drawBar(s, true)
+	c.Write([]byte("after\n"))
+
+	got, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bar := Bar(s)
+	want := "before\n" +
+		"\r\033[K" + bar +
+		"\r\033[Kduring\n" + bar +
+		"\r\033[K" + bar + "\n" +
+		"after\n"
+	if string(got) != want {
+		t.Fatalf("console output =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestLog(t *testing.T) {
+	tracker, now := newTestTracker(2, "per")
+	tracker.Record("per", Completed)
+	*now = now.Add(time.Second)
+
+	var buf bytes.Buffer
+	Log(slog.New(slog.NewTextHandler(&buf, nil)), "Progress.", tracker.Snapshot())
+	for _, field := range []string{"done=1", "total=2", "rate=1.00/s", "eta=1s", `by_scraper="per=1/0/0/2"`} {
+		if !strings.Contains(buf.String(), field) {
+			t.Errorf("expected log to contain %q, got %s", field, buf.String())
+		}
+	}
+}
@@ -0,0 +1,358 @@
+// Package report records the outcome of a scrape run as a JSON document, and
+// optionally an HTML page, so runs can be compared over time.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/progress"
+	"github.com/ysonC/multi-stocks-download/internal/scraper"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+// Task statuses.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped" // already fresh in the store
+)
+
+// LatestFile is the name of the copy of the most recent report.
+const LatestFile = "latest.json"
+
+// Report describes one scrape run.
+type Report struct {
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+	Duration   Seconds    `json:"duration_seconds"`
+	Parameters Parameters `json:"parameters"`
+	Summary    Summary    `json:"summary"`
+	// Stocks is ordered as the stocks were given to the run.
+	Stocks []Stock `json:"stocks"`
+
+	index map[string]int
+}
+
+// Parameters are the settings the run was started with.
+type Parameters struct {
+	Stocks         int      `json:"stocks"`
+	Scrapers       []string `json:"scrapers"`
+	StartDate      string   `json:"start_date"`
+	EndDate        string   `json:"end_date"`
+	Workers        int      `json:"workers"`
+	Store          string   `json:"store"`
+	Format         string   `json:"format"`
+	CombinedFormat string   `json:"combined_format"`
+}
+
+// Summary totals the run.
+type Summary struct {
+	Succeeded     int `json:"stocks_succeeded"`
+	Failed        int `json:"stocks_failed"`
+	Tasks         int `json:"tasks"`
+	TasksOK       int `json:"tasks_ok"`
+	TasksFailed   int `json:"tasks_failed"`
+	TasksSkipped  int `json:"tasks_skipped"`
+	Retries       int `json:"retries"`
+	Combined      int `json:"combined"`
+	CombineFailed int `json:"combine_failed"`
+	// DataErrors and DataWarnings count the data quality issues found in the
+	// scraped datasets.
+	DataErrors   int `json:"data_errors"`
+	DataWarnings int `json:"data_warnings"`
+}
+
+// Stock is the outcome of one stock.
+type Stock struct {
+	ID      string   `json:"id"`
+	Status  string   `json:"status"`
+	Tasks   []Task   `json:"tasks"`
+	Combine *Combine `json:"combine,omitempty"`
+	Issues  []Issue  `json:"issues,omitempty"`
+}
+
+// Task is the outcome of one scraper type of a stock.
+type Task struct {
+	Scraper   string  `json:"scraper"`
+	Status    string  `json:"status"`
+	Attempts  int     `json:"attempts"`
+	Duration  Seconds `json:"duration_seconds"`
+	Rows      int     `json:"rows,omitempty"`
+	ErrorKind string  `json:"error_kind,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	URL       string  `json:"url,omitempty"`
+}
+
+// Combine is the outcome of combining a stock's datasets.
+type Combine struct {
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Issue is a data quality problem found in one of a stock's datasets.
+type Issue struct {
+	Dataset  string `json:"dataset"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Seconds is a duration written as fractional seconds.
+type Seconds time.Duration
+
+func (s Seconds) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(s).Round(time.Millisecond).Seconds())
+}
+
+func (s *Seconds) UnmarshalJSON(data []byte) error {
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = Seconds(v * float64(time.Second))
+	return nil
+}
+
+// New starts the report of a run over stocks.
+func New(startedAt time.Time, params Parameters, stocks []string) *Report {
+	params.Stocks = len(stocks)
+	r := &Report{
+		StartedAt:  startedAt,
+		Parameters: params,
+		Stocks:     make([]Stock, len(stocks)),
+		index:      make(map[string]int, len(stocks)),
+	}
+	for i, id := range stocks {
+		r.Stocks[i] = Stock{ID: id}
+		r.index[id] = i
+	}
+	return r
+}
+
+// AddTask records the result of one scrape task. It is not safe for
+// concurrent use; scraper.ScrapeAllStocks serializes its results.
+func (r *Report) AddTask(res scraper.Result) {
+	i, ok := r.index[res.Stock]
+	if !ok {
+		return
+	}
+	t := Task{
+		Scraper:  res.Scraper,
+		Attempts: res.Attempts,
+		Duration: Seconds(res.Duration),
+		Rows:     res.Rows,
+	}
+	switch res.Outcome {
+	case progress.Completed:
+		t.Status = StatusOK
+	case progress.Skipped:
+		t.Status = StatusSkipped
+	default:
+		t.Status = StatusFailed
+		t.ErrorKind = string(res.Kind)
+		t.URL = res.URL
+		if res.Err != nil {
+			t.Error = res.Err.Error()
+		}
+	}
+	r.Stocks[i].Tasks = append(r.Stocks[i].Tasks, t)
+}
+
+// AddCombine records the results of combining stocks.
+func (r *Report) AddCombine(results []storage.CombineResult) {
+	for _, res := range results {
+		i, ok := r.index[res.Stock]
+		if !ok {
+			continue
+		}
+		c := &Combine{File: res.File}
+		if res.Err != nil {
+			c.Error = res.Err.Error()
+		}
+		r.Stocks[i].Combine = c
+	}
+}
+
+// AddIssues records the data quality issues found by storage.ValidateStock.
+func (r *Report) AddIssues(issues []storage.Issue) {
+	for _, issue := range issues {
+		i, ok := r.index[issue.Stock]
+		if !ok {
+			continue
+		}
+		r.Stocks[i].Issues = append(r.Stocks[i].Issues, Issue{
+			Dataset:  issue.Dataset,
+			Severity: string(issue.Severity),
+			Message:  issue.Message,
+		})
+	}
+}
+
+// Finish sets the end time, orders each stock's tasks as the scrapers were
+// configured, and computes the statuses and summary.
+func (r *Report) Finish(finishedAt time.Time) {
+	r.FinishedAt = finishedAt
+	r.Duration = Seconds(finishedAt.Sub(r.StartedAt))
+
+	order := func(name string) int {
+		if i := slices.Index(r.Parameters.Scrapers, name); i >= 0 {
+			return i
+		}
+		return len(r.Parameters.Scrapers)
+	}
+	var s Summary
+	for i := range r.Stocks {
+		st := &r.Stocks[i]
+		slices.SortStableFunc(st.Tasks, func(a, b Task) int { return order(a.Scraper) - order(b.Scraper) })
+		st.Status = StatusOK
+		if len(st.Tasks) < len(r.Parameters.Scrapers) {
+			st.Status = StatusFailed
+		}
+		for _, t := range st.Tasks {
+			s.Tasks++
+			if t.Attempts > 1 {
+				s.Retries += t.Attempts - 1
+			}
+			switch t.Status {
+			case StatusOK:
+				s.TasksOK++
+			case StatusSkipped:
+				s.TasksSkipped++
+			default:
+				s.TasksFailed++
+				st.Status = StatusFailed
+			}
+		}
+		if st.Status == StatusOK {
+			s.Succeeded++
+		} else {
+			s.Failed++
+		}
+		if st.Combine != nil {
+			if st.Combine.Error == "" {
+				s.Combined++
+			} else {
+				s.CombineFailed++
+			}
+		}
+		for _, issue := range st.Issues {
+			if issue.Severity == string(storage.SeverityError) {
+				s.DataErrors++
+			} else {
+				s.DataWarnings++
+			}
+		}
+	}
+	r.Summary = s
+}
+
+// Name returns the base file name of the report, derived from its start time.
+func (r *Report) Name() string {
+	return "run-" + r.StartedAt.Format("20060102-150405")
+}
+
+// WriteJSON writes the report to dir as <Name>.json and refreshes
+// latest.json. It returns the path of the dated report.
+func (r *Report) WriteJSON(dir string) (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	data = append(data, '\n')
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, r.Name()+".json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, LatestFile), data, 0o644); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// Read reads a report written by WriteJSON.
+func Read(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// WriteHTML writes the report to dir as <Name>.html and returns its path.
+func (r *Report) WriteHTML(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, r.Name()+".html")
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if err := htmlTemplate.Execute(file, r); err != nil {
+		return "", err
+	}
+	return path, file.Close()
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": func(s Seconds) string { return time.Duration(s).Round(time.Millisecond).String() },
+	"join":    strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Scrape run {{.StartedAt.Format "2006-01-02 15:04:05"}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.ok { background: #e6f4ea; }
+.skipped { background: #f1f3f4; }
+.failed, .error { background: #fce8e6; }
+.warning { background: #fef7e0; }
+</style>
+</head>
+<body>
+<h1>Scrape run {{.StartedAt.Format "2006-01-02 15:04:05"}}</h1>
+<table>
+<tr><th>Duration</th><td>{{seconds .Duration}}</td></tr>
+<tr><th>Date range</th><td>{{.Parameters.StartDate}} – {{.Parameters.EndDate}}</td></tr>
+<tr><th>Scrapers</th><td>{{join .Parameters.Scrapers ", "}}</td></tr>
+<tr><th>Workers</th><td>{{.Parameters.Workers}}</td></tr>
+<tr><th>Store / format</th><td>{{.Parameters.Store}} / {{.Parameters.Format}}</td></tr>
+<tr><th>Stocks</th><td>{{.Parameters.Stocks}}: {{.Summary.Succeeded}} succeeded, {{.Summary.Failed}} failed</td></tr>
+<tr><th>Tasks</th><td>{{.Summary.Tasks}}: {{.Summary.TasksOK}} ok, {{.Summary.TasksFailed}} failed, {{.Summary.TasksSkipped}} skipped, {{.Summary.Retries}} retries</td></tr>
+<tr><th>Combined</th><td>{{.Summary.Combined}} ok, {{.Summary.CombineFailed}} failed</td></tr>
+<tr><th>Data issues</th><td>{{.Summary.DataErrors}} errors, {{.Summary.DataWarnings}} warnings</td></tr>
+</table>
+<table>
+<tr><th>Stock</th><th>Scraper</th><th>Status</th><th>Attempts</th><th>Duration</th><th>Rows</th><th>Error</th></tr>
+{{range .Stocks}}{{$stock := .}}{{range .Tasks}}<tr class="{{.Status}}">
+<td>{{$stock.ID}}</td><td>{{.Scraper}}</td><td>{{.Status}}</td><td>{{.Attempts}}</td><td>{{seconds .Duration}}</td><td>{{.Rows}}</td>
+<td>{{if .Error}}{{.ErrorKind}}: {{.Error}}{{if .URL}} (<a href="{{.URL}}">page</a>){{end}}{{end}}</td>
+</tr>
+{{end}}{{with .Combine}}{{if .Error}}<tr class="failed"><td>{{$stock.ID}}</td><td>combine</td><td>failed</td><td></td><td></td><td></td><td>{{.Error}}</td></tr>
+{{end}}{{end}}{{end}}</table>
+{{if or .Summary.DataErrors .Summary.DataWarnings}}<table>
+<tr><th>Stock</th><th>Dataset</th><th>Severity</th><th>Issue</th></tr>
+{{range .Stocks}}{{$stock := .}}{{range .Issues}}<tr class="{{.Severity}}">
+<td>{{$stock.ID}}</td><td>{{.Dataset}}</td><td>{{.Severity}}</td><td>{{.Message}}</td>
+</tr>
+{{end}}{{end}}</table>
+{{end}}</body>
+</html>
+`))
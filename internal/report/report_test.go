@@ -0,0 +1,122 @@
+package report
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/progress"
+	"github.com/ysonC/multi-stocks-download/internal/scraper"
+	"github.com/ysonC/multi-stocks-download/internal/storage"
+)
+
+func sampleReport() *Report {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	r := New(start, Parameters{
+		Scrapers:  []string{"per", "cashflow"},
+		StartDate: "2020-01-01",
+		EndDate:   "2024-04-30",
+		Workers:   5,
+		Store:     "csv",
+		Format:    "csv",
+	}, []string{"2330", "2317"})
+
+	r.AddTask(scraper.Result{
+		Stock: "2330", Scraper: "cashflow", Outcome: progress.Completed,
+		Attempts: 2, Rows: 40, Duration: 3 * time.Second,
+	})
+	r.AddTask(scraper.Result{Stock: "2330", Scraper: "per", Outcome: progress.Skipped})
+	r.AddTask(scraper.Result{Stock: "2317", Scraper: "per", Outcome: progress.Completed, Attempts: 1})
+	r.AddTask(scraper.Result{
+		Stock: "2317", Scraper: "cashflow", Outcome: progress.Failed, Attempts: 3,
+		URL: "https://example.com/cashflow", Kind: scraper.KindTimeout, Err: errors.New("table <missing>"),
+	})
+	r.AddCombine([]storage.CombineResult{{Stock: "2330", File: "final_output/2330.csv"}})
+	r.AddIssues([]storage.Issue{
+		{Stock: "2330", Dataset: "per", Severity: storage.SeverityWarning, Message: "2 missing period(s)"},
+		{Stock: "2330", Dataset: "cashflow", Severity: storage.SeverityError, Message: "1 duplicate period(s): 24Q1"},
+	})
+	r.Finish(start.Add(90 * time.Second))
+	return r
+}
+
+func TestFinish(t *testing.T) {
+	r := sampleReport()
+	want := Summary{
+		Succeeded:    1,
+		Failed:       1,
+		Tasks:        4,
+		TasksOK:      2,
+		TasksFailed:  1,
+		TasksSkipped: 1,
+		Retries:      3,
+		Combined:     1,
+		DataErrors:   1,
+		DataWarnings: 1,
+	}
+	if r.Summary != want {
+		t.Fatalf("Summary = %+v, want %+v", r.Summary, want)
+	}
+	if r.Stocks[0].Status != StatusOK || r.Stocks[1].Status != StatusFailed {
+		t.Fatalf("unexpected stock statuses: %+v", r.Stocks)
+	}
+	if got := []string{r.Stocks[0].Tasks[0].Scraper, r.Stocks[0].Tasks[1].Scraper}; !reflect.DeepEqual(
+		got,
+		[]string{"per", "cashflow"},
+	) {
+		t.Fatalf("expected tasks in scraper order, got %v", got)
+	}
+	if r.Parameters.Stocks != 2 || r.Duration != Seconds(90*time.Second) {
+		t.Fatalf("unexpected parameters or duration: %+v %v", r.Parameters, r.Duration)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	r := sampleReport()
+	dir := filepath.Join(t.TempDir(), "reports")
+	path, err := r.WriteJSON(dir)
+	if err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+	if filepath.Base(path) != "run-20240501-090000.json" {
+		t.Fatalf("unexpected report name %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{`"duration_seconds": 90`, `"error_kind": "timeout"`, `"tasks_skipped": 1`, `"severity": "warning"`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("expected report to contain %s", s)
+		}
+	}
+
+	got, err := Read(filepath.Join(dir, LatestFile))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if got.Summary != r.Summary || !reflect.DeepEqual(got.Stocks, r.Stocks) || !got.StartedAt.Equal(r.StartedAt) {
+		t.Fatalf("latest.json round trip mismatch:\n got %+v\nwant %+v", got, r)
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	path, err := sampleReport().WriteHTML(t.TempDir())
+	if err != nil {
+		t.Fatalf("WriteHTML returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"Scrape run 2024-05-01 09:00:00", `<tr class="failed">`, "table &lt;missing&gt;", `<tr class="error">`} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("expected HTML to contain %q", s)
+		}
+	}
+}
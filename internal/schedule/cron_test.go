@@ -0,0 +1,95 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRejectsInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"0 8 * *",
+		"0 8 * * * *",
+		"60 8 * * *",
+		"0 24 * * *",
+		"0 8 0 * *",
+		"0 8 * 13 *",
+		"0 8 * * 8",
+		"0 8 5-1 * *",
+		"*/0 * * * *",
+		"0 8 * foo *",
+		"@fortnightly",
+	}
+	for _, expr := range tests {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) returned no error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	taipei := time.FixedZone("CST", 8*60*60)
+	// 2024-03-14 is a Thursday.
+	from := time.Date(2024, 3, 14, 10, 30, 0, 0, taipei)
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 8 * * sat", from, time.Date(2024, 3, 16, 8, 0, 0, 0, taipei)},
+		{"0 8 * * 6", from, time.Date(2024, 3, 16, 8, 0, 0, 0, taipei)},
+		{"0 8 11 * *", from, time.Date(2024, 4, 11, 8, 0, 0, 0, taipei)},
+		{"0 8 16 5,8,11 *", from, time.Date(2024, 5, 16, 8, 0, 0, 0, taipei)},
+		{"0 8 1 apr *", from, time.Date(2024, 4, 1, 8, 0, 0, 0, taipei)},
+		{"*/15 * * * *", from, time.Date(2024, 3, 14, 10, 45, 0, 0, taipei)},
+		{"30 10 * * *", from, time.Date(2024, 3, 15, 10, 30, 0, 0, taipei)},
+		{"0 9-17/4 * * mon-fri", from, time.Date(2024, 3, 14, 13, 0, 0, 0, taipei)},
+		{"0 0 * * 7", from, time.Date(2024, 3, 17, 0, 0, 0, 0, taipei)},
+		// Both day fields restricted: the 1st or any Monday.
+		{"0 0 1 * 1", from, time.Date(2024, 3, 18, 0, 0, 0, 0, taipei)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, taipei)},
+		{"@monthly", from, time.Date(2024, 4, 1, 0, 0, 0, 0, taipei)},
+		{"@weekly", from, time.Date(2024, 3, 17, 0, 0, 0, 0, taipei)},
+		{"0 0 30 2 *", from, time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	// 02:30 does not exist on 2024-03-10; the next day's is the first match.
+	s, err := Parse("30 2 * * *")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	got := s.Next(time.Date(2024, 3, 9, 12, 0, 0, 0, ny))
+	if want := time.Date(2024, 3, 11, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestJobNext(t *testing.T) {
+	job := Job{Name: "cashflow"}
+	for _, expr := range []string{"0 8 16 5,8,11 *", "0 8 1 4 *"} {
+		s, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned error: %v", expr, err)
+		}
+		job.Schedules = append(job.Schedules, s)
+	}
+	from := time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC)
+	if got, want := job.Next(from), time.Date(2024, 4, 1, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+)
+
+// Job is work run at the times of its schedules.
+type Job struct {
+	Name      string
+	Schedules []*Schedule
+	Run       func(ctx context.Context) error
+}
+
+// Next returns the first time after t matched by any of the job's
+// schedules, or the zero time when none matches again.
+func (j Job) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, s := range j.Schedules {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// Run runs the jobs at their scheduled times in loc until ctx is cancelled.
+// Jobs run one at a time: a job that comes due while another is running
+// starts when that one finishes, and runs once however many of its times
+// passed meanwhile. A job's error is logged and the schedule goes on.
+func Run(ctx context.Context, loc *time.Location, jobs []Job) {
+	now := time.Now().In(loc)
+	next := make([]time.Time, len(jobs))
+	for i, job := range jobs {
+		next[i] = job.Next(now)
+		slog.Info("Scheduled job.", "job", job.Name, "next", next[i])
+	}
+
+	for {
+		due := -1
+		for i, t := range next {
+			if !t.IsZero() && (due < 0 || t.Before(next[due])) {
+				due = i
+			}
+		}
+		if due < 0 {
+			slog.Warn("No job is scheduled to run again.")
+			<-ctx.Done()
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next[due]))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		job := jobs[due]
+		start := time.Now()
+		slog.Info("Starting job.", "job", job.Name)
+		if err := job.Run(ctx); err != nil {
+			slog.Error("Job failed.", "job", job.Name, logging.KeyDuration, time.Since(start), logging.KeyError, err)
+		} else {
+			slog.Info("Job finished.", "job", job.Name, logging.KeyDuration, time.Since(start))
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		next[due] = job.Next(time.Now().In(loc))
+		slog.Info("Scheduled job.", "job", job.Name, "next", next[due])
+	}
+}
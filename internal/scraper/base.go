@@ -1,70 +1,135 @@
 package scraper
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/playwright-community/playwright-go"
 
 	"github.com/ysonC/multi-stocks-download/internal/helper"
+	"github.com/ysonC/multi-stocks-download/internal/metrics"
 )
 
+// DefaultTimeout is how long fetchHTML waits for the data table when no
+// timeout is configured.
+const DefaultTimeout = 10 * time.Second
+
 // BaseScraper encapsulates shared browser and page logic.
 type BaseScraper struct {
-	pw *playwright.Playwright
+	browser *Browser
+	timeout time.Duration
 }
 
-// NewBaseScraper returns a new BaseScraper.
-func NewBaseScraper(pw *playwright.Playwright) *BaseScraper {
-	return &BaseScraper{pw: pw}
+// NewBaseScraper returns a new BaseScraper fetching pages with browser and
+// waiting up to timeout for the data table (DefaultTimeout when zero).
+func NewBaseScraper(browser *Browser, timeout time.Duration) *BaseScraper {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &BaseScraper{browser: browser, timeout: timeout}
 }
 
-// fetchHTML launches a browser, navigates to the URL, waits for the table,
-// and returns the inner HTML of the table element.
+// blockStatuses are the HTTP statuses with which the site refuses a client.
+var blockStatuses = []int{http.StatusForbidden, http.StatusTooManyRequests}
+
+// blockMarkers are texts of the pages the site shows instead of the data
+// when it throttles a client.
+var blockMarkers = []string{"瀏覽量異常", "Too Many Requests", "Access Denied"}
+
+// fetchHTML opens a page in a new context of the worker's browser, navigates
+// to the URL, waits for the table, and returns the inner HTML of the table
+// element. Errors are *ScrapeError. The fetch is recorded in the metrics.
 func (b *BaseScraper) fetchHTML(url string) (string, error) {
-	browser, err := b.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-		Headless: playwright.Bool(true),
-		Args:     []string{"--no-sandbox", "--disable-setuid-sandbox"},
-	})
+	start := time.Now()
+	html, err := b.fetch(url)
+	result := "ok"
+	if err != nil {
+		result = string(KindOf(err))
+	}
+	metrics.FetchDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+	if KindOf(err) == KindBlocked {
+		metrics.Blocks.Inc()
+	}
+	return html, err
+}
+
+func (b *BaseScraper) fetch(url string) (string, error) {
+	fail := func(kind ErrorKind, format string, err error) (string, error) {
+		return "", &ScrapeError{Kind: kind, URL: url, Err: fmt.Errorf(format, err)}
+	}
+
+	browserCtx, err := b.browser.NewContext()
 	if err != nil {
-		return "", fmt.Errorf("failed to launch browser: %w", err)
+		var se *ScrapeError
+		if errors.As(err, &se) {
+			se.URL = url
+		}
+		return "", err
 	}
-	defer browser.Close()
+	defer browserCtx.Close()
 
-	page, err := browser.NewPage()
+	page, err := browserCtx.NewPage()
 	if err != nil {
-		return "", fmt.Errorf("failed to create page: %w", err)
+		return fail(KindBrowser, "failed to create page: %w", err)
 	}
 
-	if _, err := page.Goto(url, playwright.PageGotoOptions{
+	resp, err := page.Goto(url, playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateDomcontentloaded,
-	}); err != nil {
-		return "", fmt.Errorf("failed to goto URL: %w", err)
+	})
+	if err != nil {
+		if errors.Is(err, playwright.ErrTimeout) {
+			return fail(KindTimeout, "failed to goto URL: %w", err)
+		}
+		return fail(KindNavigation, "failed to goto URL: %w", err)
+	}
+	if resp != nil && slices.Contains(blockStatuses, resp.Status()) {
+		return fail(KindBlocked, "page refused: %w", fmt.Errorf("HTTP %d", resp.Status()))
 	}
 
 	tableLocator := page.Locator("#tblDetail")
 	if err := tableLocator.WaitFor(playwright.LocatorWaitForOptions{
 		State:   playwright.WaitForSelectorStateVisible,
-		Timeout: playwright.Float(10000),
+		Timeout: playwright.Float(float64(b.timeout.Milliseconds())),
 	}); err != nil {
-		return "", fmt.Errorf("failed to get table HTML: %w", err)
+		if content, cerr := page.Content(); cerr == nil && isBlockPage(content) {
+			return fail(KindBlocked, "page refused: %w", err)
+		}
+		if errors.Is(err, playwright.ErrTimeout) {
+			return fail(KindTimeout, "failed to get table HTML: %w", err)
+		}
+		return fail(KindNoTable, "failed to get table HTML: %w", err)
 	}
 
 	html, err := tableLocator.InnerHTML()
 	if err != nil {
-		return "", fmt.Errorf("failed to get table HTML: %w", err)
+		return fail(KindNoTable, "failed to get table HTML: %w", err)
 	}
 	return html, nil
 }
 
+// isBlockPage reports whether the page content is one the site shows to
+// throttled clients.
+func isBlockPage(content string) bool {
+	for _, marker := range blockMarkers {
+		if strings.Contains(content, marker) {
+			return true
+		}
+	}
+	return false
+}
+
 // extractFullTableData parses the table HTML without skipping the header.
 func (b *BaseScraper) extractFullTableData(html string) ([][]string, error) {
 	var data [][]string
 	wrappedHTML := "<table>" + html + "</table>"
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(wrappedHTML))
 	if err != nil {
-		return nil, err
+		return nil, &ScrapeError{Kind: KindParse, Err: err}
 	}
 	doc.Find("tr").Each(func(i int, s *goquery.Selection) {
 		var row []string
@@ -92,7 +157,7 @@ func (b *BaseScraper) extractTableData(
 	wrappedHTML := "<table>" + html + "</table>"
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(wrappedHTML))
 	if err != nil {
-		return nil, err
+		return nil, &ScrapeError{Kind: KindParse, Err: err}
 	}
 	doc.Find("tr").Each(func(i int, s *goquery.Selection) {
 		if skipHeader && i == 0 {
@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+
+	"github.com/ysonC/multi-stocks-download/internal/metrics"
+)
+
+// Browser is one worker's Chromium. It is launched on first use and again
+// whenever it has disconnected, and every fetch gets a fresh context from it,
+// so no cookies or cache carry over between fetches while the browser
+// process is reused.
+type Browser struct {
+	pw *playwright.Playwright
+
+	mu      sync.Mutex
+	browser playwright.Browser
+}
+
+// NewBrowser returns a Browser launched from pw on first use.
+func NewBrowser(pw *playwright.Playwright) *Browser {
+	return &Browser{pw: pw}
+}
+
+// NewContext returns a new browser context, launching the browser when it is
+// not running. Errors are *ScrapeError of KindBrowser.
+func (b *Browser) NewContext() (playwright.BrowserContext, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.browser == nil || !b.browser.IsConnected() {
+		if b.browser != nil {
+			b.browser.Close()
+		}
+		browser, err := b.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+			Headless: playwright.Bool(true),
+			Args:     []string{"--no-sandbox", "--disable-setuid-sandbox"},
+		})
+		if err != nil {
+			b.browser = nil
+			metrics.BrowserLaunchFailures.Inc()
+			return nil, &ScrapeError{Kind: KindBrowser, Err: fmt.Errorf("failed to launch browser: %w", err)}
+		}
+		b.browser = browser
+	}
+	ctx, err := b.browser.NewContext()
+	if err != nil {
+		return nil, &ScrapeError{Kind: KindBrowser, Err: fmt.Errorf("failed to create browser context: %w", err)}
+	}
+	return ctx, nil
+}
+
+// Close closes the browser if it was launched.
+func (b *Browser) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.browser == nil {
+		return nil
+	}
+	err := b.browser.Close()
+	b.browser = nil
+	return err
+}
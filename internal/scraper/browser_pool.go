@@ -0,0 +1,58 @@
+package scraper
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// BrowserPool lends each worker a Browser of its own. Browsers are created
+// as workers first need them and stay open across runs, so a long-lived
+// process such as serve launches Chromium once per worker rather than once
+// per run; Close closes them all.
+type BrowserPool struct {
+	pw *playwright.Playwright
+
+	mu   sync.Mutex
+	free []*Browser
+	all  []*Browser
+}
+
+// NewBrowserPool returns an empty pool launching browsers from pw.
+func NewBrowserPool(pw *playwright.Playwright) *BrowserPool {
+	return &BrowserPool{pw: pw}
+}
+
+// get takes an idle browser, or a new one when every browser is lent out.
+func (p *BrowserPool) get() *Browser {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.free); n > 0 {
+		b := p.free[n-1]
+		p.free = p.free[:n-1]
+		return b
+	}
+	b := NewBrowser(p.pw)
+	p.all = append(p.all, b)
+	return b
+}
+
+// put returns a browser taken with get.
+func (p *BrowserPool) put(b *Browser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.free = append(p.free, b)
+}
+
+// Close closes every browser of the pool.
+func (p *BrowserPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for _, b := range p.all {
+		errs = append(errs, b.Close())
+	}
+	p.free, p.all = nil, nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,26 @@
+package scraper
+
+import "testing"
+
+func TestBrowserPool(t *testing.T) {
+	pool := NewBrowserPool(nil)
+	a, b := pool.get(), pool.get()
+	if a == b {
+		t.Fatal("expected two workers to get different browsers")
+	}
+	pool.put(a)
+	if got := pool.get(); got != a {
+		t.Error("expected an idle browser to be lent again")
+	}
+	pool.put(a)
+	pool.put(b)
+	if len(pool.all) != 2 {
+		t.Errorf("pool holds %d browsers, want 2", len(pool.all))
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if len(pool.all) != 0 || len(pool.free) != 0 {
+		t.Error("expected Close to empty the pool")
+	}
+}
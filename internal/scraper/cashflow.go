@@ -2,16 +2,15 @@ package scraper
 
 import (
 	"fmt"
-
-	"github.com/playwright-community/playwright-go"
+	"time"
 )
 
 type CashflowScraper struct {
 	base *BaseScraper
 }
 
-func NewCashflowScraper(pw *playwright.Playwright) *CashflowScraper {
-	base := NewBaseScraper(pw)
+func NewCashflowScraper(browser *Browser, timeout time.Duration) *CashflowScraper {
+	base := NewBaseScraper(browser, timeout)
 	return &CashflowScraper{base: base}
 }
 
@@ -2,16 +2,15 @@ package scraper
 
 import (
 	"fmt"
-
-	"github.com/playwright-community/playwright-go"
+	"time"
 )
 
 type EquityScraper struct {
 	base *BaseScraper
 }
 
-func NewEquityScraper(pw *playwright.Playwright) *EquityScraper {
-	base := NewBaseScraper(pw)
+func NewEquityScraper(browser *Browser, timeout time.Duration) *EquityScraper {
+	base := NewBaseScraper(browser, timeout)
 	return &EquityScraper{base: base}
 }
 
@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"errors"
+)
+
+// ErrorKind classifies why a scrape failed, for logs and reports.
+type ErrorKind string
+
+const (
+	KindBrowser    ErrorKind = "browser"    // launching or driving the browser failed
+	KindNavigation ErrorKind = "navigation" // the page could not be loaded
+	KindTimeout    ErrorKind = "timeout"    // the data table did not appear in time
+	KindNoTable    ErrorKind = "no_table"   // the page loaded without a readable table
+	KindBlocked    ErrorKind = "blocked"    // the site refused to serve the page
+	KindParse      ErrorKind = "parse"      // the table HTML could not be parsed
+	KindSave       ErrorKind = "save"       // a sink failed to store the data
+	KindUnknown    ErrorKind = "unknown"
+)
+
+// ScrapeError is returned by scrapers when a page cannot be fetched or read.
+type ScrapeError struct {
+	Kind ErrorKind
+	URL  string
+	Err  error
+}
+
+func (e *ScrapeError) Error() string { return e.Err.Error() }
+
+func (e *ScrapeError) Unwrap() error { return e.Err }
+
+// KindOf returns the kind of the ScrapeError wrapped by err, or KindUnknown.
+func KindOf(err error) ErrorKind {
+	var se *ScrapeError
+	if errors.As(err, &se) {
+		return se.Kind
+	}
+	return KindUnknown
+}
+
+// URLOf returns the page URL of the ScrapeError wrapped by err, if any.
+func URLOf(err error) string {
+	var se *ScrapeError
+	if errors.As(err, &se) {
+		return se.URL
+	}
+	return ""
+}
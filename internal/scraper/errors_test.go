@@ -0,0 +1,31 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestKindOf(t *testing.T) {
+	se := &ScrapeError{Kind: KindTimeout, URL: "https://example.com/per", Err: errors.New("timeout")}
+	tests := []struct {
+		name     string
+		err      error
+		wantKind ErrorKind
+		wantURL  string
+	}{
+		{"scrape error", se, KindTimeout, se.URL},
+		{"wrapped", fmt.Errorf("per 2330: %w", se), KindTimeout, se.URL},
+		{"plain", errors.New("boom"), KindUnknown, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KindOf(tt.err); got != tt.wantKind {
+				t.Errorf("KindOf() = %s, want %s", got, tt.wantKind)
+			}
+			if got := URLOf(tt.err); got != tt.wantURL {
+				t.Errorf("URLOf() = %q, want %q", got, tt.wantURL)
+			}
+		})
+	}
+}
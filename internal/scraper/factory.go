@@ -2,23 +2,27 @@ package scraper
 
 import (
 	"fmt"
-
-	"github.com/playwright-community/playwright-go"
+	"time"
 )
 
-// NewScraper returns a Scraper instance based on the given type.
-func NewScraper(scraperType string, pw *playwright.Playwright) (Scraper, error) {
+// NewScraper returns a Scraper instance based on the given type. timeout bounds
+// how long it waits for the page's data table.
+func NewScraper(
+	scraperType string,
+	browser *Browser,
+	timeout time.Duration,
+) (Scraper, error) {
 	switch scraperType {
 	case "per":
-		return NewPERScraper(pw), nil
+		return NewPERScraper(browser, timeout), nil
 	case "stockdata":
-		return NewStockDataScraper(pw), nil
+		return NewStockDataScraper(browser, timeout), nil
 	case "monthlyrevenue":
-		return NewMonthlyRevenueScraper(pw), nil
+		return NewMonthlyRevenueScraper(browser, timeout), nil
 	case "cashflow":
-		return NewCashflowScraper(pw), nil
+		return NewCashflowScraper(browser, timeout), nil
 	case "equity":
-		return NewEquityScraper(pw), nil
+		return NewEquityScraper(browser, timeout), nil
 	default:
 		return nil, fmt.Errorf("unknown scraper type: %s", scraperType)
 	}
@@ -2,16 +2,15 @@ package scraper
 
 import (
 	"fmt"
-
-	"github.com/playwright-community/playwright-go"
+	"time"
 )
 
 type MonthlyRevenueScraper struct {
 	base *BaseScraper
 }
 
-func NewMonthlyRevenueScraper(pw *playwright.Playwright) *MonthlyRevenueScraper {
-	base := NewBaseScraper(pw)
+func NewMonthlyRevenueScraper(browser *Browser, timeout time.Duration) *MonthlyRevenueScraper {
+	base := NewBaseScraper(browser, timeout)
 	return &MonthlyRevenueScraper{base: base}
 }
 
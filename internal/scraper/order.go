@@ -0,0 +1,73 @@
+package scraper
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// A Policy compares two tasks for the queue: negative puts a first.
+type Policy func(a, b Task) int
+
+// Order sorts tasks by policies, the first one deciding and later ones
+// breaking its ties. The sort is stable, so tasks the policies consider equal
+// keep their Interleave order.
+func Order(tasks []Task, policies ...Policy) {
+	if len(policies) == 0 {
+		return
+	}
+	slices.SortStableFunc(tasks, func(a, b Task) int {
+		for _, p := range policies {
+			if c := p(a, b); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// Shuffle puts tasks in random order, spreading the load over stocks and
+// pages; policies applied afterwards break their ties randomly.
+func Shuffle(tasks []Task, r *rand.Rand) {
+	r.Shuffle(len(tasks), func(i, j int) { tasks[i], tasks[j] = tasks[j], tasks[i] })
+}
+
+// ByPriority puts the tasks of stocks with a lower rank first.
+func ByPriority(rank func(stock string) int) Policy {
+	return func(a, b Task) int {
+		return cmp.Compare(rank(a.Stock), rank(b.Stock))
+	}
+}
+
+// ByStaleness puts the tasks whose data was saved longest ago first; data
+// never saved comes before everything else.
+func ByStaleness(lastSaved func(Task) (time.Time, bool)) Policy {
+	return func(a, b Task) int {
+		ta, okA := lastSaved(a)
+		tb, okB := lastSaved(b)
+		switch {
+		case !okA && !okB:
+			return 0
+		case !okA:
+			return -1
+		case !okB:
+			return 1
+		}
+		return ta.Compare(tb)
+	}
+}
+
+// FailedLast puts the tasks that failed before after the others.
+func FailedLast(failed func(Task) bool) Policy {
+	return func(a, b Task) int {
+		fa, fb := failed(a), failed(b)
+		switch {
+		case fa == fb:
+			return 0
+		case fa:
+			return 1
+		}
+		return -1
+	}
+}
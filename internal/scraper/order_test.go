@@ -0,0 +1,78 @@
+package scraper
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	stocks := []string{"1101", "2317", "2330", "2454"}
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	saved := map[string]time.Time{
+		"1101": now.Add(-time.Hour),
+		"2317": now.Add(-72 * time.Hour),
+		"2330": now.Add(-24 * time.Hour),
+		// 2454 was never saved.
+	}
+	rank := map[string]int{"2330": 0, "2454": 1}
+	byPriority := ByPriority(func(stock string) int {
+		if r, ok := rank[stock]; ok {
+			return r
+		}
+		return len(rank)
+	})
+	byStaleness := ByStaleness(func(task Task) (time.Time, bool) {
+		t, ok := saved[task.Stock]
+		return t, ok
+	})
+	failedLast := FailedLast(func(task Task) bool { return task.Stock == "2454" })
+
+	tests := []struct {
+		name     string
+		policies []Policy
+		want     []string
+	}{
+		{"input", nil, []string{"1101", "2317", "2330", "2454"}},
+		{"priority", []Policy{byPriority}, []string{"2330", "2454", "1101", "2317"}},
+		{"staleness", []Policy{byStaleness}, []string{"2454", "2317", "2330", "1101"}},
+		{"failed last", []Policy{failedLast}, []string{"1101", "2317", "2330", "2454"}},
+		{"failed last, then priority", []Policy{failedLast, byPriority}, []string{"2330", "1101", "2317", "2454"}},
+		{"priority, then staleness", []Policy{byPriority, byStaleness}, []string{"2330", "2454", "2317", "1101"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := Interleave(stocks, []string{"per"})
+			Order(tasks, tt.policies...)
+			var got []string
+			for _, task := range tasks {
+				got = append(got, task.Stock)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	tasks := Interleave([]string{"1101", "2317", "2330", "2454", "2603"}, []string{"per", "cashflow"})
+	shuffled := slices.Clone(tasks)
+	Shuffle(shuffled, rand.New(rand.NewPCG(1, 2)))
+	if reflect.DeepEqual(shuffled, tasks) {
+		t.Fatalf("expected a different order")
+	}
+	cmp := func(a, b Task) int {
+		if a.Stock+a.Scraper < b.Stock+b.Scraper {
+			return -1
+		}
+		return 1
+	}
+	slices.SortFunc(shuffled, cmp)
+	slices.SortFunc(tasks, cmp)
+	if !reflect.DeepEqual(shuffled, tasks) {
+		t.Fatalf("expected the same tasks after shuffling")
+	}
+}
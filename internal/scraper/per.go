@@ -2,16 +2,15 @@ package scraper
 
 import (
 	"fmt"
-
-	"github.com/playwright-community/playwright-go"
+	"time"
 )
 
 type PERScraper struct {
 	base *BaseScraper
 }
 
-func NewPERScraper(pw *playwright.Playwright) *PERScraper {
-	base := NewBaseScraper(pw)
+func NewPERScraper(browser *Browser, timeout time.Duration) *PERScraper {
+	base := NewBaseScraper(browser, timeout)
 	return &PERScraper{base: base}
 }
 
@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+)
+
+// Task is one scraper type of one stock.
+type Task struct {
+	Stock   string
+	Scraper string
+}
+
+// Interleave lists every scraper type of every stock in waves: the first
+// type of the first stock, then the first type of the second stock with the
+// second type of the first, and so on. Tasks running side by side therefore
+// belong to different stocks and, as far as possible, different types, so a
+// slow stock never holds several workers at once.
+func Interleave(stocks, scrapers []string) []Task {
+	type ranked struct {
+		task       Task
+		wave, kind int
+	}
+	all := make([]ranked, 0, len(stocks)*len(scrapers))
+	for i, stock := range stocks {
+		for j, scraper := range scrapers {
+			all = append(all, ranked{Task{stock, scraper}, i + j, j})
+		}
+	}
+	slices.SortStableFunc(all, func(a, b ranked) int {
+		return cmp.Or(cmp.Compare(a.wave, b.wave), cmp.Compare(a.kind, b.kind))
+	})
+	tasks := make([]Task, len(all))
+	for i, r := range all {
+		tasks[i] = r.task
+	}
+	return tasks
+}
+
+// RunPool runs work on every task with a fixed pool of workers. A dispatcher
+// feeds the tasks in order, one at a time as workers free up, and stops
+// feeding once ctx is cancelled; tasks already started still finish. Results
+// arrive on the returned channel, which is closed after the last one.
+func RunPool[T, R any](ctx context.Context, tasks []T, workers int, work func(T) R) <-chan R {
+	workers = max(1, min(workers, len(tasks)))
+	jobs := make(chan T)
+	results := make(chan R, workers)
+
+	go func() {
+		defer close(jobs)
+		for _, t := range tasks {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case jobs <- t:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				results <- work(t)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInterleave(t *testing.T) {
+	got := Interleave([]string{"A", "B", "C"}, []string{"per", "cashflow"})
+	want := []Task{
+		{"A", "per"},
+		{"B", "per"}, {"A", "cashflow"},
+		{"C", "per"}, {"B", "cashflow"},
+		{"C", "cashflow"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Interleave() = %v, want %v", got, want)
+	}
+}
+
+func TestRunPool(t *testing.T) {
+	tasks := make([]int, 50)
+	for i := range tasks {
+		tasks[i] = i
+	}
+
+	var running, peak atomic.Int32
+	results := RunPool(context.Background(), tasks, 4, func(n int) int {
+		cur := running.Add(1)
+		for {
+			p := peak.Load()
+			if cur <= p || peak.CompareAndSwap(p, cur) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+		return n * n
+	})
+
+	sum := 0
+	for r := range results {
+		sum += r
+	}
+	if want := 49 * 50 * 99 / 6; sum != want {
+		t.Fatalf("sum of results = %d, want %d", sum, want)
+	}
+	if p := peak.Load(); p > 4 {
+		t.Fatalf("expected at most 4 concurrent workers, saw %d", p)
+	}
+}
+
+func TestRunPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tasks := make([]int, 100)
+
+	var (
+		mu      sync.Mutex
+		started int
+	)
+	results := RunPool(ctx, tasks, 2, func(int) int {
+		mu.Lock()
+		started++
+		if started == 3 {
+			cancel()
+		}
+		mu.Unlock()
+		return 1
+	})
+	done := 0
+	for range results {
+		done++
+	}
+	if done < 3 || done > 5 {
+		t.Fatalf("expected the dispatcher to stop soon after cancel, %d tasks ran", done)
+	}
+}
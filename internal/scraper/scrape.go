@@ -1,101 +1,275 @@
 package scraper
 
 import (
-	"log"
-	"os"
-	"path/filepath"
-	"sync"
-
-	"github.com/playwright-community/playwright-go"
+	"context"
+	"log/slog"
+	"time"
 
+	"github.com/ysonC/multi-stocks-download/internal/checkpoint"
+	"github.com/ysonC/multi-stocks-download/internal/logging"
+	"github.com/ysonC/multi-stocks-download/internal/metrics"
+	"github.com/ysonC/multi-stocks-download/internal/progress"
 	"github.com/ysonC/multi-stocks-download/internal/storage"
 )
 
+// Options tunes how one scraper type fetches and refreshes its data.
+type Options struct {
+	// Timeout bounds the wait for the page's data table (DefaultTimeout when zero).
+	Timeout time.Duration
+	// Freshness is how old stored data may be before it is scraped again;
+	// zero means it must have been saved today.
+	Freshness time.Duration
+	// Retries is how many extra attempts a failed scrape gets.
+	Retries int
+}
+
+// retryDelay is the pause before the first retry; later retries wait longer.
+const retryDelay = 2 * time.Second
+
+// Result is the outcome of scraping one dataset of one stock.
+type Result struct {
+	Stock    string
+	Scraper  string
+	Outcome  progress.Outcome
+	Attempts int // 0 when skipped
+	Retries  int // retries allowed by the scraper's Options
+	Rows     int
+	// Started is when a worker picked the task up; Duration is how long it
+	// took, retries included.
+	Started  time.Time
+	Duration time.Duration
+	// URL and Kind describe the failure when Err is set.
+	URL  string
+	Kind ErrorKind
+	Err  error
+}
+
+// RunOptions configures a ScrapeAllStocks run.
+type RunOptions struct {
+	StartDate, EndDate string
+	// Workers is the number of concurrent browsers.
+	Workers int
+	Sink    storage.Sink
+	// Options holds the settings of each scraper type; missing types use the
+	// zero Options.
+	Options map[string]Options
+	// Record, when set, receives the Result of every task from the calling
+	// goroutine, so it needs no locking.
+	Record func(Result)
+	// Ledger, when set, keeps the state of every task; tasks it already
+	// holds as done are not scraped again and are reported as skipped.
+	Ledger *checkpoint.Ledger
+	// Order, when set, reorders the queue (see Order and Shuffle) before
+	// any task is handed out.
+	Order func(tasks []Task)
+	// Force scrapes every task, even when the stored data is fresh by the
+	// scraper's Options.Freshness.
+	Force bool
+}
+
+// ScrapeAllStocks scrapes every scraper type of every stock into run.Sink.
+// The tasks are queued in Interleave order, reordered by run.Order, and
+// handed to a fixed pool of run.Workers workers, each borrowing a browser of
+// its own from browsers for the whole run. Cancelling ctx stops
+// handing out tasks and retrying; the tasks already running finish their
+// current attempt and the rest count as failed. It returns the stocks whose every type succeeded and the stocks
+// with at least one failure.
 func ScrapeAllStocks(
-	pw *playwright.Playwright,
+	ctx context.Context,
+	browsers *BrowserPool,
 	stocks, scraperTypes []string,
-	startDate, endDate string,
-	maxWorkers int,
-	downloadDir string,
+	run RunOptions,
 ) ([]string, []string) {
-	var (
-		wg           sync.WaitGroup
-		mutex        sync.Mutex
-		successCount = make(map[string]int)
-		totalTypes   = len(scraperTypes)
-	)
+	successCount := make(map[string]int, len(stocks))
+	record := func(res Result) {
+		if res.Err == nil {
+			successCount[res.Stock]++
+		}
+		if run.Record != nil {
+			run.Record(res)
+		}
+		metrics.Tasks.WithLabelValues(res.Scraper, res.Outcome.String()).Inc()
+	}
 
-	for _, stock := range stocks {
-		successCount[stock] = 0
+	var queue []Task
+	for _, task := range Interleave(stocks, scraperTypes) {
+		if run.Ledger.State(ledgerTask(task, run)) == checkpoint.Done {
+			record(Result{Stock: task.Stock, Scraper: task.Scraper, Outcome: progress.Skipped})
+			continue
+		}
+		queue = append(queue, task)
+	}
+	if run.Order != nil {
+		run.Order(queue)
 	}
 
-	sem := make(chan struct{}, maxWorkers)
+	metrics.QueueDepth.Set(float64(len(queue)))
+	defer metrics.QueueDepth.Set(0)
+	results := RunPool(ctx, queue, run.Workers, func(task Task) Result {
+		metrics.QueueDepth.Dec()
+		metrics.ActiveWorkers.Inc()
+		defer metrics.ActiveWorkers.Dec()
+		browser := browsers.get()
+		defer browsers.put(browser)
+		lt := ledgerTask(task, run)
+		markTask(run.Ledger, lt, checkpoint.Running)
+		res := scrapeTask(ctx, browser, task, run.StartDate, run.EndDate, run.Sink, run.Options[task.Scraper], run.Force)
+		if res.Err == nil {
+			markTask(run.Ledger, lt, checkpoint.Done)
+		} else {
+			markTask(run.Ledger, lt, checkpoint.Failed)
+		}
+		return res
+	})
+	for res := range results {
+		record(res)
+	}
+	if ctx.Err() != nil {
+		slog.Warn("Run interrupted; remaining tasks were not started.", logging.KeyError, context.Cause(ctx))
+	}
 
-	for _, stock := range stocks {
-		for _, sType := range scraperTypes {
-			wg.Add(1)
-			sem <- struct{}{}
-			go func(stockNumber, scraperType string) {
-				defer wg.Done()
-				defer func() { <-sem }()
-
-				stockOutputDir := filepath.Join(downloadDir, stockNumber)
-				os.MkdirAll(stockOutputDir, 0755)
-
-				outputFile := filepath.Join(stockOutputDir, scraperType+".csv")
-				if storage.IsFileUpToDate(outputFile) {
-					log.Printf("%s (%s) up-to-date, skipped.", stockNumber, scraperType)
-					mutex.Lock()
-					successCount[stockNumber]++
-					mutex.Unlock()
-					return
-				}
-
-				instance, err := NewScraper(scraperType, pw)
-				if err != nil {
-					log.Printf("Scraper creation error (%s) %s: %v", scraperType, stockNumber, err)
-					return
-				}
-
-				data, err := instance.Scrape(stockNumber, startDate, endDate)
-				if err != nil {
-					log.Printf("Scraping error (%s) %s: %v", scraperType, stockNumber, err)
-					return
-				}
-
-				if err := storage.WriteCSV(outputFile, data); err != nil {
-					log.Printf("CSV save error (%s) %s: %v", scraperType, stockNumber, err)
-					return
-				}
-
-				mutex.Lock()
-				successCount[stockNumber]++
-				mutex.Unlock()
-
-				log.Printf("Successfully scraped %s: %s", scraperType, stockNumber)
-			}(stock, sType)
+	return checkDownloadStocks(stocks, successCount, len(scraperTypes))
+}
+
+// ledgerTask returns the checkpoint entry of task.
+func ledgerTask(task Task, run RunOptions) checkpoint.Task {
+	return checkpoint.Task{
+		Stock:     task.Stock,
+		Scraper:   task.Scraper,
+		StartDate: run.StartDate,
+		EndDate:   run.EndDate,
+	}
+}
+
+// markTask records the state of task in ledger; a ledger that cannot be
+// written only costs the ability to resume, so the run goes on.
+func markTask(ledger *checkpoint.Ledger, task checkpoint.Task, state checkpoint.State) {
+	if err := ledger.Mark(task, state); err != nil {
+		slog.Warn(
+			"Failed to update checkpoint.",
+			logging.KeyStock, task.Stock,
+			logging.KeyScraper, task.Scraper,
+			logging.KeyError, err,
+		)
+	}
+}
+
+// scrapeTask scrapes one dataset of one stock into sink, retrying failures
+// as opts allows until ctx is cancelled, and logs the outcome. Fresh data is
+// skipped unless force is set.
+func scrapeTask(
+	ctx context.Context,
+	browser *Browser,
+	task Task,
+	startDate, endDate string,
+	sink storage.Sink,
+	opts Options,
+	force bool,
+) Result {
+	stockNumber, scraperType := task.Stock, task.Scraper
+	start := time.Now()
+	res := Result{Stock: stockNumber, Scraper: scraperType, Retries: opts.Retries, Started: start}
+	logger := slog.With(logging.KeyStock, stockNumber, logging.KeyScraper, scraperType)
+	if !force && sink.IsUpToDate(stockNumber, scraperType, opts.Freshness) {
+		logger.Debug("Up-to-date, skipped.")
+		res.Outcome = progress.Skipped
+		res.Duration = time.Since(start)
+		return res
+	}
+
+	fail := func(err error) Result {
+		res.Outcome = progress.Failed
+		res.Duration = time.Since(start)
+		res.URL, res.Kind, res.Err = URLOf(err), KindOf(err), err
+		return res
+	}
+
+	instance, err := NewScraper(scraperType, browser, opts.Timeout)
+	if err != nil {
+		logger.Error("Scraper creation failed.", logging.KeyError, err)
+		return fail(err)
+	}
+
+	var data [][]string
+	for attempt := 0; ; attempt++ {
+		attemptStart := time.Now()
+		res.Attempts++
+		data, err = instance.Scrape(stockNumber, startDate, endDate)
+		if err == nil || attempt >= opts.Retries {
+			break
 		}
+		metrics.Retries.WithLabelValues(scraperType).Inc()
+		logger.Warn(
+			"Scraping failed, retrying.",
+			logging.KeyAttempt, res.Attempts,
+			"retries", opts.Retries,
+			logging.KeyDuration, time.Since(attemptStart),
+			logging.KeyURL, URLOf(err),
+			logging.KeyErrorKind, KindOf(err),
+			logging.KeyError, err,
+		)
+		if !sleep(ctx, retryDelay*time.Duration(attempt+1)) {
+			break
+		}
+	}
+	if err != nil {
+		logger.Error(
+			"Scraping failed.",
+			logging.KeyAttempt, res.Attempts,
+			logging.KeyDuration, time.Since(start),
+			logging.KeyURL, URLOf(err),
+			logging.KeyErrorKind, KindOf(err),
+			logging.KeyError, err,
+		)
+		return fail(err)
 	}
 
-	wg.Wait()
+	if err := sink.Save(stockNumber, scraperType, data); err != nil {
+		logger.Error(
+			"Save failed.",
+			logging.KeyErrorKind, KindSave,
+			logging.KeyError, err,
+		)
+		return fail(&ScrapeError{Kind: KindSave, Err: err})
+	}
 
-	// Return successful and error stocks
-	return checkDownloadStocks(successCount, totalTypes)
+	res.Outcome = progress.Completed
+	res.Rows = len(data)
+	res.Duration = time.Since(start)
+	logger.Debug(
+		"Scraped.",
+		logging.KeyAttempt, res.Attempts,
+		"rows", res.Rows,
+		logging.KeyDuration, res.Duration,
+	)
+	return res
 }
 
-func checkDownloadStocks(successCount map[string]int, totalTypes int) ([]string, []string) {
+// sleep waits for d, returning false when ctx is cancelled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func checkDownloadStocks(stocks []string, successCount map[string]int, totalTypes int) ([]string, []string) {
 	var successfulStocks []string
 	var errorStocks []string
-	for stock, count := range successCount {
-		if count == totalTypes {
+	for _, stock := range stocks {
+		if successCount[stock] == totalTypes {
 			successfulStocks = append(successfulStocks, stock)
 		} else {
 			errorStocks = append(errorStocks, stock)
-			log.Printf("Incomplete data for stock %s; skipping combine.", stock)
+			slog.Warn("Incomplete data; skipping combine.", logging.KeyStock, stock)
 		}
 	}
 	if len(errorStocks) > 0 {
-		log.Println("Some tasks failed. Please check the logs for more information.")
+		slog.Warn("Some tasks failed. Please check the logs for more information.", "stocks", len(errorStocks))
 	}
 	return successfulStocks, errorStocks
 }
@@ -0,0 +1,57 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/progress"
+)
+
+// freshSink reports every dataset as up to date.
+type freshSink struct{}
+
+func (s *freshSink) IsUpToDate(stock, dataset string, maxAge time.Duration) bool { return true }
+
+func (s *freshSink) LastSaved(stock, dataset string) (time.Time, bool) { return time.Now(), true }
+
+func (s *freshSink) Save(stock, dataset string, data [][]string) error { return nil }
+
+func TestScrapeAllStocksForce(t *testing.T) {
+	// An unknown scraper type fails as soon as it is attempted, without a
+	// browser, which tells an attempted task from a skipped one.
+	tests := []struct {
+		force bool
+		want  progress.Outcome
+	}{
+		{false, progress.Skipped},
+		{true, progress.Failed},
+	}
+	for _, tt := range tests {
+		var outcomes []progress.Outcome
+		ScrapeAllStocks(context.Background(), NewBrowserPool(nil), []string{"2330"}, []string{"bogus"}, RunOptions{
+			Workers: 1,
+			Sink:    &freshSink{},
+			Record:  func(res Result) { outcomes = append(outcomes, res.Outcome) },
+			Force:   tt.force,
+		})
+		if len(outcomes) != 1 || outcomes[0] != tt.want {
+			t.Errorf("Force=%v: outcomes = %v, want [%v]", tt.force, outcomes, tt.want)
+		}
+	}
+}
+
+func TestSleepCancel(t *testing.T) {
+	if !sleep(context.Background(), time.Millisecond) {
+		t.Error("sleep() = false, want true when the wait completes")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if sleep(ctx, time.Minute) {
+		t.Error("sleep() = true, want false when ctx is cancelled")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleep took %v after cancellation", elapsed)
+	}
+}
@@ -2,8 +2,7 @@ package scraper
 
 import (
 	"fmt"
-
-	"github.com/playwright-community/playwright-go"
+	"time"
 )
 
 // StockDataScraper implements the scraper for stock data.
@@ -12,8 +11,8 @@ type StockDataScraper struct {
 }
 
 // NewStockDataScraper returns a new StockDataScraper.
-func NewStockDataScraper(pw *playwright.Playwright) *StockDataScraper {
-	base := NewBaseScraper(pw)
+func NewStockDataScraper(browser *Browser, timeout time.Duration) *StockDataScraper {
+	base := NewBaseScraper(browser, timeout)
 	return &StockDataScraper{base: base}
 }
 
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// DefaultTolerance is the relative difference CrossCheckStock accepts between
+// prices of the same period in two datasets.
+const DefaultTolerance = 0.01
+
+// pctTolerance is the difference in percentage points up to which two weekly
+// changes are considered equal.
+const pctTolerance = 0.05
+
+// periodRow is one record of a dataset with its parsed period and the number
+// columns that parse, keyed by column key.
+type periodRow struct {
+	Period period
+	Values map[string]float64
+}
+
+// loadPeriodRows reads one of a stock's datasets into rows keyed by period
+// index. Records with an unrecognized period are left out. It returns false
+// when the dataset cannot be read.
+func loadPeriodRows(downloadDir, stock, dataset string) (map[int]periodRow, bool) {
+	d, err := LookupDataset(dataset)
+	if err != nil {
+		return nil, false
+	}
+	data, err := ReadDataset(downloadDir, stock, dataset)
+	if err != nil {
+		return nil, false
+	}
+	rows := make(map[int]periodRow)
+	for _, record := range d.Records(data) {
+		p, ok := parsePeriod(record[0])
+		if !ok {
+			continue
+		}
+		row := periodRow{Period: p, Values: make(map[string]float64)}
+		for i, col := range d.Columns {
+			if n, ok := parseNumber(record[i]); ok && col.Type == NumberColumn {
+				row.Values[col.Key] = n
+			}
+		}
+		rows[p.Index] = row
+	}
+	return rows, true
+}
+
+// span returns the first and last trading day of a weekly or monthly period.
+func (p period) span() (time.Time, time.Time) {
+	switch p.Kind {
+	case 'W':
+		monday := time.Unix((int64(p.Index)*7+4)*86400, 0).UTC()
+		return monday, monday.AddDate(0, 0, 4)
+	case 'M':
+		first := time.Date(p.Index/12, time.Month(p.Index%12+1), 1, 0, 0, 0, 0, time.UTC)
+		return first, first.AddDate(0, 1, -1)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// within reports whether a and b differ by at most tolerance relative to the
+// larger of the two.
+func within(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+// CrossCheckStock compares the fields that overlap between a stock's datasets
+// and reports discrepancies beyond tolerance as warnings:
+//
+//   - per and stockdata must agree on the weekly close;
+//   - the monthly close in monthlyrevenue must lie within the weekly lows and
+//     highs of stockdata for that month, its high must not be below, and its
+//     low not above, those of the weeks inside the month.
+//
+// A pair is only checked when both datasets are among datasets (default: all)
+// and can be read; ValidateStock reports missing ones. Prices that differ
+// while their weekly changes agree point at one dataset being adjusted for
+// dividends and splits and the other not (goodinfo.tw's PRICE_ADJ).
+func CrossCheckStock(downloadDir, stock string, tolerance float64, datasets ...string) []Issue {
+	if len(datasets) == 0 {
+		datasets = DatasetNames()
+	}
+	load := func(name string) (map[int]periodRow, bool) {
+		if !slices.Contains(datasets, name) {
+			return nil, false
+		}
+		return loadPeriodRows(downloadDir, stock, name)
+	}
+
+	var issues []Issue
+	weeks, okWeeks := load("stockdata")
+	if per, ok := load("per"); ok && okWeeks {
+		if msg := compareWeeklyCloses(per, weeks, tolerance); msg != "" {
+			issues = append(issues, Issue{stock, "per/stockdata", SeverityWarning, msg})
+		}
+	}
+	if months, ok := load("monthlyrevenue"); ok && okWeeks {
+		if msg := compareMonthlyPrices(months, weeks, tolerance); msg != "" {
+			issues = append(issues, Issue{stock, "monthlyrevenue/stockdata", SeverityWarning, msg})
+		}
+	}
+	return issues
+}
+
+// compareWeeklyCloses compares the closes of per and stockdata week by week
+// and describes the mismatches, or returns "" when they agree.
+func compareWeeklyCloses(per, weeks map[int]periodRow, tolerance float64) string {
+	var (
+		compared, mismatched int
+		largest              float64
+		worst                string
+		changesAgree         = true
+	)
+	for _, idx := range slices.Sorted(maps.Keys(per)) {
+		a, b := per[idx], weeks[idx]
+		closeA, okA := a.Values["close"]
+		closeB, okB := b.Values["close"]
+		if !okA || !okB {
+			continue
+		}
+		compared++
+		if within(closeA, closeB, tolerance) {
+			continue
+		}
+		mismatched++
+		if diff := math.Abs(closeA-closeB) / math.Max(math.Abs(closeA), math.Abs(closeB)); diff > largest {
+			largest = diff
+			worst = fmt.Sprintf("%s (%g vs %g)", a.Period.Label, closeA, closeB)
+		}
+		pctA, okA := a.Values["change_pct"]
+		pctB, okB := b.Values["change_pct"]
+		if !okA || !okB || math.Abs(pctA-pctB) > pctTolerance {
+			changesAgree = false
+		}
+	}
+	if mismatched == 0 {
+		return ""
+	}
+	msg := fmt.Sprintf("close differs by more than %g%% in %d of %d week(s), largest at %s",
+		tolerance*100, mismatched, compared, worst)
+	if changesAgree {
+		msg += "; the weekly changes agree, so one of them is likely price-adjusted (PRICE_ADJ) and the other not"
+	}
+	return msg
+}
+
+// compareMonthlyPrices checks the monthly close, high and low against the
+// weekly lows and highs of the same month and describes the months that do
+// not fit, or returns "" when they all do.
+func compareMonthlyPrices(months, weeks map[int]periodRow, tolerance float64) string {
+	type week struct {
+		start, end time.Time
+		high, low  float64
+	}
+	var ws []week
+	for _, row := range weeks {
+		high, okHigh := row.Values["high"]
+		low, okLow := row.Values["low"]
+		if okHigh && okLow {
+			start, end := row.Period.span()
+			ws = append(ws, week{start, end, high, low})
+		}
+	}
+
+	var (
+		compared int
+		problems []string
+	)
+	for _, idx := range slices.Sorted(maps.Keys(months)) {
+		m := months[idx]
+		start, end := m.Period.span()
+		var (
+			overlap, inside bool
+			lo, hi          = math.Inf(1), math.Inf(-1)
+			insideLo        = math.Inf(1)
+			insideHi        = math.Inf(-1)
+		)
+		for _, w := range ws {
+			if w.end.Before(start) || w.start.After(end) {
+				continue
+			}
+			overlap = true
+			lo, hi = math.Min(lo, w.low), math.Max(hi, w.high)
+			if !w.start.Before(start) && !w.end.After(end) {
+				inside = true
+				insideLo, insideHi = math.Min(insideLo, w.low), math.Max(insideHi, w.high)
+			}
+		}
+		if !overlap {
+			continue
+		}
+		compared++
+		label := m.Period.Label
+		if c, ok := m.Values["close"]; ok && (c < lo*(1-tolerance) || c > hi*(1+tolerance)) {
+			problems = append(problems, fmt.Sprintf("%s close %g outside %g-%g", label, c, lo, hi))
+			continue
+		}
+		if !inside {
+			continue
+		}
+		if h, ok := m.Values["high"]; ok && insideHi > h*(1+tolerance) {
+			problems = append(problems, fmt.Sprintf("%s high %g below weekly high %g", label, h, insideHi))
+		} else if l, ok := m.Values["low"]; ok && insideLo < l*(1-tolerance) {
+			problems = append(problems, fmt.Sprintf("%s low %g above weekly low %g", label, l, insideLo))
+		}
+	}
+	if len(problems) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("monthly prices do not fit the weekly prices in %d of %d month(s): %s",
+		len(problems), compared, summarize(problems))
+}
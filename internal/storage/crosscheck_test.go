@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCrossCheckStock(t *testing.T) {
+	// period, trading days, open, high, low, close, change, change (%)
+	weeks := [][]string{
+		{"24W05", "5", "590", "600", "585", "595", "+5", "+0.85%"},
+		{"24W04", "5", "580", "592", "578", "590", "+10", "+1.72%"},
+		{"24W03", "5", "575", "585", "570", "580", "+5", "+0.87%"},
+		{"24W02", "5", "570", "580", "565", "575", "+5", "+0.88%"},
+		{"24W01", "5", "560", "575", "555", "570", "+10", "+1.79%"},
+	}
+	// period, close, change, change (%), river EPS, PER
+	per := [][]string{
+		{"24W05", "595", "+5", "+0.85%", "32", "18"},
+		{"24W04", "590", "+10", "+1.72%", "32", "18"},
+		{"24W03", "580", "+5", "+0.87%", "32", "18"},
+		{"24W02", "575", "+5", "+0.88%", "32", "18"},
+		{"24W01", "570", "+10", "+1.79%", "32", "18"},
+	}
+	unadjusted := [][]string{
+		{"24W05", "595", "+5", "+0.85%", "32", "18"},
+		{"24W04", "590", "+10", "+1.72%", "32", "18"},
+		{"24W03", "600", "+5", "+0.87%", "32", "18"},
+		{"24W02", "595", "+5", "+0.88%", "32", "18"},
+		{"24W01", "590", "+10", "+1.79%", "32", "18"},
+	}
+	// period, open, close, high, low
+	months := [][]string{{"2024/01", "560", "590", "592", "555"}}
+	badMonths := [][]string{{"2024/01", "560", "650", "660", "555"}}
+
+	tests := []struct {
+		name     string
+		per      [][]string
+		months   [][]string
+		datasets []string
+		want     []Issue
+	}{
+		{"consistent", per, months, nil, nil},
+		{"price adjustment", unadjusted, months, nil, []Issue{{
+			"2330", "per/stockdata", SeverityWarning,
+			"close differs by more than 1% in 3 of 5 week(s), largest at 24W01 (590 vs 570); " +
+				"the weekly changes agree, so one of them is likely price-adjusted (PRICE_ADJ) and the other not",
+		}}},
+		{"monthly close", per, badMonths, nil, []Issue{{
+			"2330", "monthlyrevenue/stockdata", SeverityWarning,
+			"monthly prices do not fit the weekly prices in 1 of 1 month(s): 2024/01 close 650 outside 555-600",
+		}}},
+		{"datasets filter", unadjusted, badMonths, []string{"monthlyrevenue"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			sink := NewCSVSink(dir)
+			for dataset, data := range map[string][][]string{
+				"stockdata":      weeks,
+				"per":            tt.per,
+				"monthlyrevenue": tt.months,
+			} {
+				if err := sink.Save("2330", dataset, data); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got := CrossCheckStock(dir, "2330", DefaultTolerance, tt.datasets...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CrossCheckStock() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodSpan(t *testing.T) {
+	tests := []struct {
+		label      string
+		start, end string
+	}{
+		{"99W52", "1999-12-27", "1999-12-31"},
+		{"00W01", "2000-01-03", "2000-01-07"},
+		{"99M12", "1999-12-01", "1999-12-31"},
+		{"2024/02", "2024-02-01", "2024-02-29"},
+	}
+	for _, tt := range tests {
+		p, ok := parsePeriod(tt.label)
+		if !ok {
+			t.Fatalf("parsePeriod(%s) failed", tt.label)
+		}
+		start, end := p.span()
+		if got := start.Format("2006-01-02"); got != tt.start {
+			t.Errorf("span(%s) start = %s, want %s", tt.label, got, tt.start)
+		}
+		if got := end.Format("2006-01-02"); got != tt.end {
+			t.Errorf("span(%s) end = %s, want %s", tt.label, got, tt.end)
+		}
+	}
+}
@@ -3,35 +3,90 @@ package storage
 import (
 	"encoding/csv"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/ysonC/multi-stocks-download/internal/logging"
 )
 
-func CombineSuccessfulStocks(stocks []string, downloadDir, finalOutputDir string) error {
+// CombineResult is the outcome of combining one stock.
+type CombineResult struct {
+	Stock string
+	File  string
+	Err   error
+}
+
+// CombineSuccessfulStocks merges each stock's datasets into one file in
+// finalOutputDir, written in one of CombinedFormats. CSV and XLSX outputs get
+// header rows in headerLang (see HeaderLangs). A stock that fails to combine
+// is logged and reported in its result; the others are still combined.
+func CombineSuccessfulStocks(
+	stocks []string,
+	downloadDir, finalOutputDir, format, headerLang string,
+) ([]CombineResult, error) {
+	if !slices.Contains(CombinedFormats, format) {
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+	if !slices.Contains(HeaderLangs, headerLang) {
+		return nil, fmt.Errorf("unknown header language: %s", headerLang)
+	}
+	results := make([]CombineResult, 0, len(stocks))
 	for _, stock := range stocks {
-		stockDir := filepath.Join(downloadDir, stock)
-		finalOutput := filepath.Join(finalOutputDir, stock+".csv")
-		if err := combineAllCSVInFolder(stockDir, finalOutput); err != nil {
-			log.Printf("Error combining stock %s: %v", stock, err)
+		finalOutput := filepath.Join(finalOutputDir, stock+"."+format)
+		var err error
+		switch format {
+		case "parquet":
+			err = combineStockParquet(downloadDir, stock, finalOutput)
+		case "json", "ndjson":
+			err = combineStockJSON(downloadDir, stock, finalOutput, format == "ndjson")
+		case "xlsx":
+			err = combineStockXLSX(downloadDir, stock, finalOutput, headerLang)
+		default:
+			err = combineAllCSVInFolder(downloadDir, stock, finalOutput, headerLang)
+		}
+		results = append(results, CombineResult{Stock: stock, File: finalOutput, Err: err})
+		if err != nil {
+			slog.Error("Combining failed.", logging.KeyStock, stock, logging.KeyError, err)
 			continue
 		}
-		log.Printf("Successfully combined data for stock %s", stock)
+		slog.Info("Combined.", logging.KeyStock, stock, "format", format)
 	}
-	return nil
+	return results, nil
+}
+
+// IsFileUpToDate checks if the file exists and is fresh according to IsFresh.
+func IsFileUpToDate(filePath string, maxAge time.Duration) bool {
+	t, ok := fileModTime(filePath)
+	return ok && IsFresh(t, maxAge)
 }
 
-// IsFileUpToDate checks if the file exists and was modified today.
-func IsFileUpToDate(filePath string) bool {
+// fileModTime returns the modification time of filePath, if it exists.
+func fileModTime(filePath string) (time.Time, bool) {
 	info, err := os.Stat(filePath)
 	if err != nil {
-		return false
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// IsFresh reports whether data saved at t is younger than maxAge. A zero
+// maxAge keeps the original rule: data is fresh when it was saved today.
+func IsFresh(t time.Time, maxAge time.Duration) bool {
+	if maxAge <= 0 {
+		return IsToday(t)
 	}
+	return time.Since(t) < maxAge
+}
+
+// IsToday reports whether t falls on the current local calendar day.
+func IsToday(t time.Time) bool {
 	now := time.Now()
-	modTime := info.ModTime()
-	return now.Year() == modTime.Year() && now.YearDay() == modTime.YearDay()
+	t = t.In(now.Location())
+	return now.Year() == t.Year() && now.YearDay() == t.YearDay()
 }
 
 func ReadDirFiles(folderPath string) ([]string, error) {
@@ -98,75 +153,49 @@ func CheckFileExist(fileNames []string) error {
 	return nil
 }
 
-func combineAllCSVInFolder(folderPath, finalOutput string) error {
-	files, err := ReadDirFiles(folderPath)
-	if err != nil {
-		return fmt.Errorf("failed to read directory: %v", err)
-	}
-
-	err = CheckFileExist(files)
-	if err != nil {
-		return fmt.Errorf("failed to check file existence: %v", err)
-	}
+// headerFuncs prepends the Chinese header rows of each dataset.
+var headerFuncs = map[string]func([][]string) ([][]string, error){
+	"per":            addPERHeaderNew,
+	"stockdata":      addStockDataHeader,
+	"monthlyrevenue": addMonthlyRevenueHeader,
+	"cashflow":       addCashflowHeader,
+	"equity":         addEquityHeader,
+}
 
-	perData, err := ReadCSV(folderPath + "/per.csv")
-	if err != nil {
-		return fmt.Errorf("failed to read per.csv: %v", err)
-	}
-	stockData, err := ReadCSV(folderPath + "/stockdata.csv")
-	if err != nil {
-		return fmt.Errorf("failed to read stockdata.csv: %v", err)
-	}
-	monthlyRevenueData, err := ReadCSV(folderPath + "/monthlyrevenue.csv")
-	if err != nil {
-		return fmt.Errorf("failed to read monthlyrevenue.csv: %v", err)
-	}
-	cashflowData, err := ReadCSV(folderPath + "/cashflow.csv")
-	if err != nil {
-		return fmt.Errorf("failed to read cashflow.csv: %v", err)
-	}
-	equityData, err := ReadCSV(folderPath + "/equity.csv")
-	if err != nil {
-		return fmt.Errorf("failed to read equity.csv: %v", err)
+// loadHeadedTables reads every dataset of a stock and prepends its header
+// rows in the requested language, returning the tables in combine order.
+func loadHeadedTables(downloadDir, stock, lang string) ([][][]string, error) {
+	var tables [][][]string
+	for _, d := range Datasets {
+		data, err := ReadDataset(downloadDir, stock, d.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", d.Name, err)
+		}
+		data, err = headerFuncs[d.Name](data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add header to %s data: %v", d.Name, err)
+		}
+		data, err = localizeHeader(d, data, lang)
+		if err != nil {
+			return nil, fmt.Errorf("failed to localize %s header: %v", d.Name, err)
+		}
+		tables = append(tables, data)
 	}
+	return tables, nil
+}
 
-	// Add headers to the data
-	perData, err = addPERHeaderNew(perData)
-	if err != nil {
-		return fmt.Errorf("failed to add header to per data: %v", err)
-	}
-	stockData, err = addStockDataHeader(stockData)
-	if err != nil {
-		return fmt.Errorf("failed to add header to stock data: %v", err)
-	}
-	monthlyRevenueData, err = addMonthlyRevenueHeader(monthlyRevenueData)
-	if err != nil {
-		return fmt.Errorf("failed to add header to monthly revenue data: %v", err)
-	}
-	cashflowData, err = addCashflowHeader(cashflowData)
-	if err != nil {
-		return fmt.Errorf("failed to add header to cashflow data: %v", err)
-	}
-	equityData, err = addEquityHeader(equityData)
+func combineAllCSVInFolder(downloadDir, stock, finalOutput, lang string) error {
+	tables, err := loadHeadedTables(downloadDir, stock, lang)
 	if err != nil {
-		return fmt.Errorf("failed to add header to equity data: %v", err)
+		return err
 	}
 
-	mergedData, err := mergeCSVData(perData, stockData)
-	if err != nil {
-		return fmt.Errorf("failed to merge per and stock data: %v", err)
-	}
-	mergedData, err = mergeCSVData(mergedData, monthlyRevenueData)
-	if err != nil {
-		return fmt.Errorf("failed to merge monthly revenue data: %v", err)
-	}
-	mergedData, err = mergeCSVData(mergedData, cashflowData)
-	if err != nil {
-		return fmt.Errorf("failed to merge cashflow data: %v", err)
-	}
-	mergedData, err = mergeCSVData(mergedData, equityData)
-	if err != nil {
-		return fmt.Errorf("failed to merge equity data: %v", err)
+	mergedData := tables[0]
+	for i, table := range tables[1:] {
+		mergedData, err = mergeCSVData(mergedData, table)
+		if err != nil {
+			return fmt.Errorf("failed to merge %s data: %v", Datasets[i+1].Name, err)
+		}
 	}
 
 	// Write the final finalOutput
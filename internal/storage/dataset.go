@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ColumnType is the value type of a column in typed outputs such as Parquet.
+type ColumnType int
+
+const (
+	// TextColumn holds free text such as periods and dates.
+	TextColumn ColumnType = iota
+	// NumberColumn holds a decimal number; "-" and unparsable cells become nulls.
+	NumberColumn
+)
+
+// Column describes one leaf column of a scraped table.
+type Column struct {
+	// Key is the stable snake_case identifier used for database columns and
+	// JSON fields.
+	Key string
+	// Title is the Chinese leaf header shown on goodinfo.tw.
+	Title string
+	// EnglishTitle is the English display name of Title.
+	EnglishTitle string
+	Type         ColumnType
+}
+
+// Dataset describes the table produced by one scraper type and doubles as its
+// bilingual header dictionary. The first column always holds the period
+// (week, month or quarter) the row belongs to.
+type Dataset struct {
+	Name string
+	// Source is the goodinfo.tw page the dataset is scraped from.
+	Source  string
+	Columns []Column
+}
+
+// Datasets lists every table the scrapers produce, in combine order.
+var Datasets = []Dataset{
+	{
+		Name:   "per",
+		Source: "https://goodinfo.tw/tw/ShowK_ChartFlow.asp?RPT_CAT=PER",
+		Columns: []Column{
+			{"period", "交易週別", "Week", TextColumn},
+			{"close", "收盤價", "Close", NumberColumn},
+			{"change", "漲跌價", "Change", NumberColumn},
+			{"change_pct", "漲跌幅", "Change (%)", NumberColumn},
+			{"river_eps", "河流圖 EPS(元)", "River Chart EPS (TWD)", NumberColumn},
+			{"per", "目前 PER (倍)", "Current PER (x)", NumberColumn},
+		},
+	},
+	{
+		Name:   "stockdata",
+		Source: "https://goodinfo.tw/tw/ShowK_Chart.asp",
+		Columns: []Column{
+			{"period", "交易週別", "Week", TextColumn},
+			{"trading_days", "交易日數", "Trading Days", NumberColumn},
+			{"open", "開盤", "Open", NumberColumn},
+			{"high", "最高", "High", NumberColumn},
+			{"low", "最低", "Low", NumberColumn},
+			{"close", "收盤", "Close", NumberColumn},
+			{"change", "漲跌", "Change", NumberColumn},
+			{"change_pct", "漲跌(%)", "Change (%)", NumberColumn},
+			{"amplitude_pct", "振幅(%)", "Amplitude (%)", NumberColumn},
+			{"volume", "千張", "Volume (K Lots)", NumberColumn},
+			{"volume_daily_avg", "日均", "Volume Daily Avg", NumberColumn},
+			{"turnover", "億元", "Turnover (100M TWD)", NumberColumn},
+			{"turnover_daily_avg", "日均", "Turnover Daily Avg", NumberColumn},
+			{"foreign_net", "外資", "Foreign", NumberColumn},
+			{"trust_net", "投信", "Investment Trust", NumberColumn},
+			{"dealer_net", "自營", "Dealer", NumberColumn},
+			{"institutional_net", "合計", "Total", NumberColumn},
+			{"foreign_holding_pct", "外資持股(%)", "Foreign Holding (%)", NumberColumn},
+			{"margin_change", "增減", "Margin Change", NumberColumn},
+			{"margin_balance", "餘額", "Margin Balance", NumberColumn},
+			{"short_change", "增減", "Short Change", NumberColumn},
+			{"short_balance", "餘額", "Short Balance", NumberColumn},
+			{"short_margin_ratio_pct", "券資比(%)", "Short/Margin Ratio (%)", NumberColumn},
+		},
+	},
+	{
+		Name:   "monthlyrevenue",
+		Source: "https://goodinfo.tw/tw/ShowSaleMonChart.asp",
+		Columns: []Column{
+			{"period", "月別", "Month", TextColumn},
+			{"open", "開盤", "Open", NumberColumn},
+			{"close", "收盤", "Close", NumberColumn},
+			{"high", "最高", "High", NumberColumn},
+			{"low", "最低", "Low", NumberColumn},
+			{"change", "漲跌(元)", "Change (TWD)", NumberColumn},
+			{"change_pct", "漲跌(%)", "Change (%)", NumberColumn},
+			{"revenue", "營收(億)", "Revenue (100M)", NumberColumn},
+			{"revenue_mom_pct", "月增(%)", "MoM (%)", NumberColumn},
+			{"revenue_yoy_pct", "年增(%)", "YoY (%)", NumberColumn},
+			{"revenue_cum", "營收(億)", "Cumulative Revenue (100M)", NumberColumn},
+			{"revenue_cum_yoy_pct", "年增(%)", "Cumulative YoY (%)", NumberColumn},
+			{"consolidated_revenue", "營收(億)", "Consolidated Revenue (100M)", NumberColumn},
+			{"consolidated_revenue_mom_pct", "月增(%)", "Consolidated MoM (%)", NumberColumn},
+			{"consolidated_revenue_yoy_pct", "年增(%)", "Consolidated YoY (%)", NumberColumn},
+			{"consolidated_revenue_cum", "營收(億)", "Consolidated Cumulative Revenue (100M)", NumberColumn},
+			{"consolidated_revenue_cum_yoy_pct", "年增(%)", "Consolidated Cumulative YoY (%)", NumberColumn},
+		},
+	},
+	{
+		Name:   "cashflow",
+		Source: "https://goodinfo.tw/tw/StockCashFlow.asp",
+		Columns: []Column{
+			{"period", "季度", "Quarter", TextColumn},
+			{"avg_capital", "平均股本(億)", "Avg Share Capital (100M)", NumberColumn},
+			{"report_score", "財報評分", "Report Score", NumberColumn},
+			{"prev_close", "上期收盤", "Previous Close", NumberColumn},
+			{"close", "本期收盤", "Close", NumberColumn},
+			{"change", "漲跌(元)", "Change (TWD)", NumberColumn},
+			{"change_pct", "漲跌(%)", "Change (%)", NumberColumn},
+			{"pretax_income", "稅前淨利", "Pre-tax Income", NumberColumn},
+			{"net_income", "稅後淨利", "Net Income", NumberColumn},
+			{"operating_cash_flow", "營業活動", "Operating", NumberColumn},
+			{"investing_cash_flow", "投資活動", "Investing", NumberColumn},
+			{"financing_cash_flow", "融資活動", "Financing", NumberColumn},
+			{"other_cash_flow", "其他活動", "Other", NumberColumn},
+			{"net_cash_flow", "淨現金流", "Net Cash Flow", NumberColumn},
+			{"free_cash_flow", "自由金流", "Free Cash Flow", NumberColumn},
+			{"cash_begin", "期初餘額", "Beginning Balance", NumberColumn},
+			{"cash_end", "期末餘額", "Ending Balance", NumberColumn},
+			{"cash_flow_pct", "現金流量(%)", "Cash Flow (%)", NumberColumn},
+			{"eps", "稅後EPS(元)", "EPS After Tax (TWD)", NumberColumn},
+		},
+	},
+	{
+		Name:   "equity",
+		Source: "https://goodinfo.tw/tw/EquityDistributionClassHis.asp",
+		Columns: []Column{
+			{"period", "週別", "Week", TextColumn},
+			{"stat_date", "統計日期", "Statistics Date", TextColumn},
+			{"close", "收盤", "Close", NumberColumn},
+			{"change", "漲跌(元)", "Change (TWD)", NumberColumn},
+			{"change_pct", "漲跌(%)", "Change (%)", NumberColumn},
+			{"custody_shares", "集保庫存(萬張)", "Custody Shares (10K Lots)", NumberColumn},
+			{"holders_le_10", "≦10張", "<=10 Lots", NumberColumn},
+			{"holders_10_50", ">10張≦50張", ">10 <=50 Lots", NumberColumn},
+			{"holders_50_100", ">50張≦100張", ">50 <=100 Lots", NumberColumn},
+			{"holders_100_200", ">100張≦200張", ">100 <=200 Lots", NumberColumn},
+			{"holders_200_400", ">200張≦400張", ">200 <=400 Lots", NumberColumn},
+			{"holders_400_800", ">400張≦800張", ">400 <=800 Lots", NumberColumn},
+			{"holders_800_1000", ">800張≦1000張", ">800 <=1000 Lots", NumberColumn},
+			{"holders_gt_1000", ">1000張", ">1000 Lots", NumberColumn},
+		},
+	},
+}
+
+// DatasetNames returns the names of all datasets in combine order.
+func DatasetNames() []string {
+	names := make([]string, len(Datasets))
+	for i, d := range Datasets {
+		names[i] = d.Name
+	}
+	return names
+}
+
+// LookupDataset returns the dataset definition for the given scraper type.
+func LookupDataset(name string) (Dataset, error) {
+	for _, d := range Datasets {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return Dataset{}, fmt.Errorf("unknown dataset: %s", name)
+}
+
+// Records drops repeated header and blank rows from scraped data and pads or
+// truncates every remaining row to the dataset's column count.
+func (d Dataset) Records(data [][]string) [][]string {
+	var records [][]string
+	for _, row := range data {
+		if len(row) == 0 {
+			continue
+		}
+		period := row[0]
+		if period == "" || period == "-" || period == d.Columns[0].Title ||
+			period == d.Columns[0].EnglishTitle {
+			continue
+		}
+		record := make([]string, len(d.Columns))
+		for i := range record {
+			if i < len(row) {
+				record[i] = row[i]
+			} else {
+				record[i] = "-"
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// isBlank reports whether a cell holds no value.
+func isBlank(s string) bool {
+	s = strings.TrimSpace(s)
+	return s == "" || s == "-"
+}
+
+// parseNumber parses a goodinfo.tw number such as "+1,234.5" or "12.3%".
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, ",", "")
+	s = strings.TrimPrefix(s, "+")
+	s = strings.TrimSuffix(s, "%")
+	if s == "" || s == "-" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
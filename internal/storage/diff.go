@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DatasetDiff counts the rows of one stock's dataset that differ between two
+// snapshots of the download directory.
+type DatasetDiff struct {
+	Stock   string
+	Dataset string
+	Added   int
+	Removed int
+	// Changed counts the rows present in both snapshots with at least one
+	// changed cell; Cells counts those cells.
+	Changed int
+	Cells   int
+}
+
+// CellChange is one cell whose value differs between two snapshots.
+type CellChange struct {
+	Stock   string
+	Dataset string
+	Period  string
+	Column  string
+	Old     string
+	New     string
+}
+
+// SnapshotDiff is the difference between two snapshots of the download
+// directory.
+type SnapshotDiff struct {
+	// Datasets holds only the datasets that differ, ordered by stock and then
+	// in combine order.
+	Datasets []DatasetDiff
+	Cells    []CellChange
+}
+
+// DiffSnapshots compares the given datasets (default: all) of every stock in
+// two download directories. Rows are matched by period; a repeated period is
+// matched by its occurrence. Number cells are compared by value, so "1,090"
+// and "1090" are equal.
+func DiffSnapshots(oldDir, newDir string, datasets []string) (*SnapshotDiff, error) {
+	if len(datasets) == 0 {
+		datasets = DatasetNames()
+	}
+	var ds []Dataset
+	for _, name := range datasets {
+		d, err := LookupDataset(name)
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+
+	oldStocks, err := ListStocks(oldDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks in %s: %w", oldDir, err)
+	}
+	newStocks, err := ListStocks(newDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks in %s: %w", newDir, err)
+	}
+	stocks := append(oldStocks, newStocks...)
+	slices.Sort(stocks)
+	stocks = slices.Compact(stocks)
+
+	diff := &SnapshotDiff{}
+	for _, stock := range stocks {
+		for _, d := range ds {
+			oldRecords, err := readRecords(oldDir, stock, d)
+			if err != nil {
+				return nil, err
+			}
+			newRecords, err := readRecords(newDir, stock, d)
+			if err != nil {
+				return nil, err
+			}
+			diff.add(stock, d, oldRecords, newRecords)
+		}
+	}
+	return diff, nil
+}
+
+// readRecords reads the records of a stock's dataset, or none when the
+// dataset does not exist.
+func readRecords(downloadDir, stock string, d Dataset) ([][]string, error) {
+	data, err := ReadDataset(downloadDir, stock, d.Name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of %s in %s: %w", d.Name, stock, downloadDir, err)
+	}
+	return d.Records(data), nil
+}
+
+// rowKeys returns the key of every record: its period, suffixed with the
+// occurrence number when the period repeats.
+func rowKeys(records [][]string) []string {
+	keys := make([]string, len(records))
+	seen := make(map[string]int)
+	for i, record := range records {
+		seen[record[0]]++
+		keys[i] = record[0]
+		if n := seen[record[0]]; n > 1 {
+			keys[i] += "#" + strconv.Itoa(n)
+		}
+	}
+	return keys
+}
+
+func (s *SnapshotDiff) add(stock string, d Dataset, oldRecords, newRecords [][]string) {
+	dd := DatasetDiff{Stock: stock, Dataset: d.Name}
+	oldByKey := make(map[string][]string, len(oldRecords))
+	for i, key := range rowKeys(oldRecords) {
+		oldByKey[key] = oldRecords[i]
+	}
+	matched := make(map[string]bool, len(newRecords))
+	for i, key := range rowKeys(newRecords) {
+		newRecord := newRecords[i]
+		oldRecord, ok := oldByKey[key]
+		if !ok {
+			dd.Added++
+			continue
+		}
+		matched[key] = true
+		changed := false
+		for c := 1; c < len(d.Columns); c++ {
+			if sameCell(d.Columns[c], oldRecord[c], newRecord[c]) {
+				continue
+			}
+			changed = true
+			dd.Cells++
+			s.Cells = append(s.Cells, CellChange{
+				Stock:   stock,
+				Dataset: d.Name,
+				Period:  newRecord[0],
+				Column:  d.Columns[c].Key,
+				Old:     oldRecord[c],
+				New:     newRecord[c],
+			})
+		}
+		if changed {
+			dd.Changed++
+		}
+	}
+	dd.Removed = len(oldByKey) - len(matched)
+	if dd.Added+dd.Removed+dd.Changed > 0 {
+		s.Datasets = append(s.Datasets, dd)
+	}
+}
+
+// sameCell reports whether two values of a column are equal. Blank cells ("-"
+// or empty) are equal to each other, and number cells are compared by value.
+func sameCell(col Column, a, b string) bool {
+	a, b = strings.TrimSpace(a), strings.TrimSpace(b)
+	if a == b || (isBlank(a) && isBlank(b)) {
+		return true
+	}
+	if col.Type != NumberColumn {
+		return false
+	}
+	x, okA := parseNumber(a)
+	y, okB := parseNumber(b)
+	return okA && okB && x == y
+}
+
+// Totals sums the row and cell counts over all datasets.
+func (s *SnapshotDiff) Totals() DatasetDiff {
+	var t DatasetDiff
+	for _, d := range s.Datasets {
+		t.Added += d.Added
+		t.Removed += d.Removed
+		t.Changed += d.Changed
+		t.Cells += d.Cells
+	}
+	return t
+}
+
+// WriteCellsCSV writes the changed cells as CSV with a header row.
+func (s *SnapshotDiff) WriteCellsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"stock_id", "dataset", "period", "column", "old", "new"})
+	for _, c := range s.Cells {
+		cw.Write([]string{c.Stock, c.Dataset, c.Period, c.Column, c.Old, c.New})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	save := func(sink Sink, stock, dataset string, data [][]string) {
+		t.Helper()
+		if err := sink.Save(stock, dataset, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldSink := NewCSVSink(oldDir)
+	save(oldSink, "2330", "per", [][]string{
+		{"24W02", "1,090", "+10", "+1.72%", "-", "18.3"},
+		{"24W01", "1,080", "0", "0%", "32", "18.1"},
+		{"23W52", "1,070", "0", "0%", "32", "18.0"},
+	})
+	save(oldSink, "2317", "per", [][]string{{"24W01", "104", "-", "-", "9.5", "10.9"}})
+
+	// The new snapshot is Parquet, so only values, not formatting, may differ.
+	newSink := NewParquetSink(newDir)
+	save(newSink, "2330", "per", [][]string{
+		{"24W03", "1,100", "+10", "+0.92%", "32.3", "18.4"},
+		{"24W02", "1090", "+10", "+1.72", "", "18.3"},
+		{"24W01", "1,075", "-5", "0%", "32", "18.1"},
+	})
+	save(newSink, "1101", "per", [][]string{{"24W01", "32", "-", "-", "2", "15"}})
+
+	diff, err := DiffSnapshots(oldDir, newDir, []string{"per", "cashflow"})
+	if err != nil {
+		t.Fatalf("DiffSnapshots returned error: %v", err)
+	}
+	wantDatasets := []DatasetDiff{
+		{Stock: "1101", Dataset: "per", Added: 1},
+		{Stock: "2317", Dataset: "per", Removed: 1},
+		{Stock: "2330", Dataset: "per", Added: 1, Removed: 1, Changed: 1, Cells: 2},
+	}
+	if !reflect.DeepEqual(diff.Datasets, wantDatasets) {
+		t.Errorf("Datasets = %+v, want %+v", diff.Datasets, wantDatasets)
+	}
+	if got, want := diff.Totals(), (DatasetDiff{Added: 2, Removed: 2, Changed: 1, Cells: 2}); got != want {
+		t.Errorf("Totals() = %+v, want %+v", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := diff.WriteCellsCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "stock_id,dataset,period,column,old,new\n" +
+		"2330,per,24W01,close,\"1,080\",1075\n" +
+		"2330,per,24W01,change,0,-5\n"
+	if buf.String() != want {
+		t.Errorf("WriteCellsCSV() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestRowKeys(t *testing.T) {
+	got := rowKeys([][]string{{"24W02"}, {"24W01"}, {"24W02"}, {"24W02"}})
+	want := []string{"24W02", "24W01", "24W02#2", "24W02#3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("rowKeys() = %v, want %v", got, want)
+	}
+}
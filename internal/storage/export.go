@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// panelWriter receives the rows of one exported panel file.
+type panelWriter interface {
+	WriteRow(row []string) error
+	Close() error
+}
+
+// csvPanelWriter writes a header row followed by the panel rows.
+type csvPanelWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newCSVPanelWriter(path string, columns []string) (*csvPanelWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(columns); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &csvPanelWriter{file: file, writer: writer}, nil
+}
+
+func (c *csvPanelWriter) WriteRow(row []string) error {
+	return c.writer.Write(row)
+}
+
+func (c *csvPanelWriter) Close() error {
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
+
+func newPanelWriter(path, format string, columns []tableColumn) (panelWriter, error) {
+	switch format {
+	case "csv":
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = col.Name
+		}
+		return newCSVPanelWriter(path, names)
+	case "parquet":
+		return newParquetTableWriter(path, columns)
+	default:
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+}
+
+// ExportPanels walks every stock folder in downloadDir and writes one file per
+// dataset into outputDir, covering all stocks. The "long" layout emits tidy
+// (stock_id, period, column, value) rows; the "wide" layout emits one row per
+// stock and period with a column per field, typed when written as Parquet.
+// Missing values ("-") are exported as empty cells. It returns the paths of
+// the files written.
+func ExportPanels(downloadDir, outputDir, layout, format string, datasets []string) ([]string, error) {
+	if layout != "long" && layout != "wide" {
+		return nil, fmt.Errorf("unknown export layout: %s", layout)
+	}
+	if format != "csv" && format != "parquet" {
+		return nil, fmt.Errorf("unknown export format: %s", format)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	stocks, err := ListStocks(downloadDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks: %w", err)
+	}
+
+	var written []string
+	for _, name := range datasets {
+		d, err := LookupDataset(name)
+		if err != nil {
+			return written, err
+		}
+		path := filepath.Join(outputDir, fmt.Sprintf("%s_%s.%s", d.Name, layout, format))
+		rows, err := exportDataset(d, downloadDir, stocks, path, layout, format)
+		if err != nil {
+			return written, fmt.Errorf("failed to export %s: %w", d.Name, err)
+		}
+		slog.Info("Exported dataset.", "dataset", d.Name, "rows", rows, "file", path)
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+func exportDataset(d Dataset, downloadDir string, stocks []string, path, layout, format string) (int, error) {
+	columns := []tableColumn{
+		{Name: "stock_id"},
+		{Name: "period"},
+		{Name: "column"},
+		{Name: "value"},
+	}
+	if layout == "wide" {
+		columns = append([]tableColumn{{Name: "stock_id"}}, datasetColumns(d, "")...)
+	}
+
+	w, err := newPanelWriter(path, format, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	for _, stock := range stocks {
+		data, err := ReadDataset(downloadDir, stock, d.Name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			w.Close()
+			return rows, fmt.Errorf("failed to read %s for stock %s: %w", d.Name, stock, err)
+		}
+		for _, record := range d.Records(data) {
+			for i, v := range record {
+				if v == "-" {
+					record[i] = ""
+				}
+			}
+			if layout == "wide" {
+				if err := w.WriteRow(append([]string{stock}, record...)); err != nil {
+					w.Close()
+					return rows, err
+				}
+				rows++
+				continue
+			}
+			for i, col := range d.Columns[1:] {
+				if err := w.WriteRow([]string{stock, record[0], col.Key, record[i+1]}); err != nil {
+					w.Close()
+					return rows, err
+				}
+				rows++
+			}
+		}
+	}
+	return rows, w.Close()
+}
+
+// ListStocks returns the sorted ids of the stocks in downloadDir, covering
+// both per-stock CSV folders and Hive-style Parquet partitions.
+func ListStocks(downloadDir string) ([]string, error) {
+	entries, err := os.ReadDir(downloadDir)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if !strings.HasPrefix(entry.Name(), "dataset=") {
+			seen[entry.Name()] = true
+			continue
+		}
+		partitions, err := os.ReadDir(filepath.Join(downloadDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range partitions {
+			if stock, ok := strings.CutPrefix(p.Name(), "stock_id="); ok && p.IsDir() {
+				seen[stock] = true
+			}
+		}
+	}
+	stocks := make([]string, 0, len(seen))
+	for stock := range seen {
+		stocks = append(stocks, stock)
+	}
+	sort.Strings(stocks)
+	return stocks, nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func writeTestStock(t *testing.T, dir, stock string, data [][]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, stock), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteCSV(filepath.Join(dir, stock, "per.csv"), data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportPanels(t *testing.T) {
+	downloadDir := t.TempDir()
+	writeTestStock(t, downloadDir, "2330", [][]string{
+		{"24W02", "590", "+10", "+1.72", "32.3", "18.3"},
+	})
+	writeTestStock(t, downloadDir, "2317", [][]string{
+		{"24W02", "104", "-", "-", "9.5", "10.9"},
+	})
+
+	tests := []struct {
+		name   string
+		layout string
+		want   [][]string
+	}{
+		{
+			name:   "Wide",
+			layout: "wide",
+			want: [][]string{
+				{"stock_id", "period", "close", "change", "change_pct", "river_eps", "per"},
+				{"2317", "24W02", "104", "", "", "9.5", "10.9"},
+				{"2330", "24W02", "590", "+10", "+1.72", "32.3", "18.3"},
+			},
+		},
+		{
+			name:   "Long",
+			layout: "long",
+			want: [][]string{
+				{"stock_id", "period", "column", "value"},
+				{"2317", "24W02", "close", "104"},
+				{"2317", "24W02", "change", ""},
+				{"2317", "24W02", "change_pct", ""},
+				{"2317", "24W02", "river_eps", "9.5"},
+				{"2317", "24W02", "per", "10.9"},
+				{"2330", "24W02", "close", "590"},
+				{"2330", "24W02", "change", "+10"},
+				{"2330", "24W02", "change_pct", "+1.72"},
+				{"2330", "24W02", "river_eps", "32.3"},
+				{"2330", "24W02", "per", "18.3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outDir := t.TempDir()
+			files, err := ExportPanels(downloadDir, outDir, tt.layout, "csv", []string{"per", "cashflow"})
+			if err != nil {
+				t.Fatalf("ExportPanels returned error: %v", err)
+			}
+			if len(files) != 2 {
+				t.Fatalf("expected 2 files, got %v", files)
+			}
+			got, err := ReadCSV(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExportPanels() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Parquet", func(t *testing.T) {
+		outDir := t.TempDir()
+		files, err := ExportPanels(downloadDir, outDir, "long", "parquet", []string{"per"})
+		if err != nil {
+			t.Fatalf("ExportPanels returned error: %v", err)
+		}
+		f, err := os.Open(files[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		info, _ := f.Stat()
+		pf, err := parquet.OpenFile(f, info.Size())
+		if err != nil {
+			t.Fatalf("failed to open parquet output: %v", err)
+		}
+		if pf.NumRows() != 10 {
+			t.Errorf("expected 10 parquet rows, got %d", pf.NumRows())
+		}
+	})
+}
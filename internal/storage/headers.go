@@ -0,0 +1,75 @@
+package storage
+
+import "fmt"
+
+// HeaderLangs lists the header languages of combined outputs: Chinese only,
+// English only, or the Chinese rows followed by an English leaf row.
+var HeaderLangs = []string{"zh", "en", "both"}
+
+// groupTitles translates the grouping rows printed above the leaf headers.
+var groupTitles = map[string]string{
+	"成交張數":      "Volume",
+	"成交金額":      "Turnover",
+	"法人買賣超(千張)": "Institutional Net Buy (K Lots)",
+	"融資(千張)":    "Margin (K Lots)",
+	"融券(千張)":    "Short (K Lots)",
+	"當月股價":      "Monthly Price",
+	"營業收入":      "Revenue",
+	"合併營業收入":    "Consolidated Revenue",
+	"單月":        "Monthly",
+	"累計":        "Cumulative",
+	"季度股價":      "Quarterly Price",
+	"獲利(億)":     "Profit (100M)",
+	"現金流量(億)":   "Cash Flow (100M)",
+	"現金餘額(億)":   "Cash Balance (100M)",
+	"當週股價":      "Weekly Price",
+}
+
+// localizeHeader rewrites the header rows that an add*Header function put in
+// front of a dataset's data. "zh" keeps them as they are, "en" translates the
+// grouping rows and replaces the leaf row with English titles, and "both"
+// inserts the English leaf row under the Chinese one.
+func localizeHeader(d Dataset, data [][]string, lang string) ([][]string, error) {
+	leaf := -1
+	for i := 0; i < len(data) && i < 4; i++ {
+		if len(data[i]) > 0 && data[i][0] == d.Columns[0].Title {
+			leaf = i
+			break
+		}
+	}
+	if leaf < 0 {
+		return nil, fmt.Errorf("no %s header row found", d.Name)
+	}
+
+	english := make([]string, len(data[leaf]))
+	for i, title := range data[leaf] {
+		english[i] = title
+		if i < len(d.Columns) {
+			english[i] = d.Columns[i].EnglishTitle
+		}
+	}
+
+	var out [][]string
+	switch lang {
+	case "zh":
+		return data, nil
+	case "en":
+		for _, row := range data[:leaf] {
+			translated := make([]string, len(row))
+			for i, title := range row {
+				translated[i] = title
+				if en, ok := groupTitles[title]; ok {
+					translated[i] = en
+				}
+			}
+			out = append(out, translated)
+		}
+		out = append(out, english)
+	case "both":
+		out = append(out, data[:leaf+1]...)
+		out = append(out, english)
+	default:
+		return nil, fmt.Errorf("unknown header language: %s", lang)
+	}
+	return append(out, data[leaf+1:]...), nil
+}
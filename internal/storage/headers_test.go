@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLocalizeHeader(t *testing.T) {
+	d, _ := LookupDataset("per")
+	data, _ := addPERHeaderNew([][]string{{"24W02", "590", "+10", "+1.72", "32.3", "18.3"}})
+
+	zhLeaf := []string{"交易週別", "收盤價", "漲跌價", "漲跌幅", "河流圖 EPS(元)", "目前 PER (倍)"}
+	enLeaf := []string{"Week", "Close", "Change", "Change (%)", "River Chart EPS (TWD)", "Current PER (x)"}
+	row := []string{"24W02", "590", "+10", "+1.72", "32.3", "18.3"}
+	blank := []string{"", "", "", "", "", ""}
+
+	tests := []struct {
+		name    string
+		lang    string
+		want    [][]string
+		wantErr bool
+	}{
+		{name: "Chinese", lang: "zh", want: [][]string{blank, blank, zhLeaf, row}},
+		{name: "English", lang: "en", want: [][]string{blank, blank, enLeaf, row}},
+		{name: "Both", lang: "both", want: [][]string{blank, blank, zhLeaf, enLeaf, row}},
+		{name: "Unknown", lang: "jp", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := localizeHeader(d, data, tt.lang)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("localizeHeader() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("localizeHeader() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalizeHeaderTranslatesGroups(t *testing.T) {
+	d, _ := LookupDataset("monthlyrevenue")
+	data, _ := addMonthlyRevenueHeader(nil)
+
+	got, err := localizeHeader(d, data, "en")
+	if err != nil {
+		t.Fatalf("localizeHeader() error = %v", err)
+	}
+	if got[0][1] != "Monthly Price" || got[0][7] != "Revenue" || got[1][10] != "Cumulative" {
+		t.Errorf("group rows not translated: %v", got[:2])
+	}
+	if got[2][0] != "Month" || got[2][16] != "Consolidated Cumulative YoY (%)" {
+		t.Errorf("leaf row not translated: %v", got[2])
+	}
+}
+
+func TestCombineSuccessfulStocksXLSX(t *testing.T) {
+	downloadDir := t.TempDir()
+	sink := NewCSVSink(downloadDir)
+	for _, d := range Datasets {
+		row := []string{"24W01"}
+		for range d.Columns[1:] {
+			row = append(row, "1.5")
+		}
+		if err := sink.Save("2330", d.Name, [][]string{row}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outDir := t.TempDir()
+	results, err := CombineSuccessfulStocks([]string{"2330"}, downloadDir, outDir, "xlsx", "en")
+	if err != nil {
+		t.Fatalf("CombineSuccessfulStocks returned error: %v", err)
+	}
+	if len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("unexpected combine results: %+v", results)
+	}
+
+	f, err := excelize.OpenFile(filepath.Join(outDir, "2330.xlsx"))
+	if err != nil {
+		t.Fatalf("failed to open workbook: %v", err)
+	}
+	defer f.Close()
+
+	names := sheetNames["en"]
+	if got := f.GetSheetList(); !reflect.DeepEqual(got, names[:]) {
+		t.Fatalf("sheet list = %v, want %v", got, names)
+	}
+	rows, err := f.GetRows("Prices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[2][0] != "Week" || rows[3][1] != "1.5" {
+		t.Errorf("unexpected Prices sheet rows: %v", rows)
+	}
+}
@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RecordMeta is the source metadata attached to every JSON record.
+type RecordMeta struct {
+	StockID   string
+	Dataset   string
+	Source    string
+	ScrapedAt time.Time
+}
+
+// jsonField is one key/value pair of a JSON record, kept in output order.
+type jsonField struct {
+	key   string
+	value any
+}
+
+// JSONRecordWriter streams dataset records as a JSON array or as
+// newline-delimited JSON, one object per record. Objects carry the stock id,
+// dataset, period, one field per column keyed by its English identifier, and
+// the source metadata. Records are written as they arrive, so large histories
+// are never held in memory.
+type JSONRecordWriter struct {
+	w      *bufio.Writer
+	ndjson bool
+	count  int
+}
+
+// NewJSONRecordWriter returns a writer emitting NDJSON when ndjson is set and
+// a JSON array otherwise. Close must be called to terminate the output.
+func NewJSONRecordWriter(w io.Writer, ndjson bool) *JSONRecordWriter {
+	return &JSONRecordWriter{w: bufio.NewWriter(w), ndjson: ndjson}
+}
+
+// Write encodes one record of dataset d. Number columns are emitted as JSON
+// numbers, or null when the cell is "-"; cells that are not numbers keep
+// their text as a JSON string, so nothing is lost on the way back.
+func (j *JSONRecordWriter) Write(meta RecordMeta, d Dataset, record []string) error {
+	switch {
+	case j.ndjson:
+	case j.count == 0:
+		j.w.WriteString("[\n")
+	default:
+		j.w.WriteString(",\n")
+	}
+	j.count++
+
+	fields := []jsonField{
+		{"stock_id", meta.StockID},
+		{"dataset", meta.Dataset},
+	}
+	for i, col := range d.Columns {
+		v := "-"
+		if i < len(record) {
+			v = record[i]
+		}
+		var value any
+		switch {
+		case isBlank(v):
+		case col.Type == NumberColumn:
+			if n, ok := parseNumber(v); ok {
+				value = n
+			} else {
+				value = v
+			}
+		default:
+			value = v
+		}
+		fields = append(fields, jsonField{col.Key, value})
+	}
+	fields = append(fields,
+		jsonField{"source", meta.Source},
+		jsonField{"scraped_at", meta.ScrapedAt.Format(time.RFC3339)},
+	)
+
+	j.w.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return err
+		}
+		j.w.Write(key)
+		j.w.WriteByte(':')
+		j.w.Write(value)
+	}
+	j.w.WriteByte('}')
+	if j.ndjson {
+		j.w.WriteByte('\n')
+	}
+	return nil
+}
+
+// Close terminates the JSON array if needed and flushes buffered output.
+func (j *JSONRecordWriter) Close() error {
+	if !j.ndjson {
+		if j.count == 0 {
+			j.w.WriteString("[")
+		}
+		j.w.WriteString("\n]\n")
+	}
+	return j.w.Flush()
+}
+
+// WriteJSON writes the records of one stock's dataset to filePath as a JSON
+// array, or as NDJSON when ndjson is set.
+func WriteJSON(filePath string, stock string, d Dataset, data [][]string, ndjson bool) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	meta := RecordMeta{StockID: stock, Dataset: d.Name, Source: d.Source, ScrapedAt: time.Now()}
+	w := NewJSONRecordWriter(file, ndjson)
+	for _, record := range d.Records(data) {
+		if err := w.Write(meta, d, record); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ReadJSON reads a file written by WriteJSON (array or NDJSON) back into string
+// rows in the dataset's column order. Nulls are returned as "-".
+func ReadJSON(filePath string, d Dataset) ([][]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(file))
+	dec.UseNumber()
+
+	var data [][]string
+	readObject := func() error {
+		var obj map[string]any
+		if err := dec.Decode(&obj); err != nil {
+			return err
+		}
+		record := make([]string, len(d.Columns))
+		for i, col := range d.Columns {
+			switch v := obj[col.Key].(type) {
+			case nil:
+				record[i] = "-"
+			case json.Number:
+				record[i] = v.String()
+			case string:
+				record[i] = v
+			default:
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		data = append(data, record)
+		return nil
+	}
+
+	tok, err := dec.Token()
+	if errors.Is(err, io.EOF) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); ok && delim == '[' {
+		for dec.More() {
+			if err := readObject(); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+
+	// NDJSON: the first token opened an object, so rewind and decode line by line.
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	dec = json.NewDecoder(bufio.NewReader(file))
+	dec.UseNumber()
+	for {
+		err := readObject()
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// JSONSink writes one JSON (or NDJSON) file per stock and dataset under
+// Dir/<stock>/<dataset>.json or .ndjson.
+type JSONSink struct {
+	Dir    string
+	NDJSON bool
+}
+
+// NewJSONSink returns a JSONSink rooted at dir.
+func NewJSONSink(dir string, ndjson bool) *JSONSink {
+	return &JSONSink{Dir: dir, NDJSON: ndjson}
+}
+
+func (j *JSONSink) path(stock, dataset string) string {
+	ext := ".json"
+	if j.NDJSON {
+		ext = ".ndjson"
+	}
+	return filepath.Join(j.Dir, stock, dataset+ext)
+}
+
+func (j *JSONSink) IsUpToDate(stock, dataset string, maxAge time.Duration) bool {
+	return IsFileUpToDate(j.path(stock, dataset), maxAge)
+}
+
+func (j *JSONSink) LastSaved(stock, dataset string) (time.Time, bool) {
+	return fileModTime(j.path(stock, dataset))
+}
+
+func (j *JSONSink) Save(stock, dataset string, data [][]string) error {
+	d, err := LookupDataset(dataset)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(j.Dir, stock), 0o755); err != nil {
+		return err
+	}
+	return WriteJSON(j.path(stock, dataset), stock, d, data, j.NDJSON)
+}
+
+// combineStockJSON streams every dataset of a stock into one JSON file; each
+// record carries its dataset name so consumers can split them again.
+func combineStockJSON(downloadDir, stock, finalOutput string, ndjson bool) error {
+	file, err := os.Create(finalOutput)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := NewJSONRecordWriter(file, ndjson)
+	for _, d := range Datasets {
+		data, err := ReadDataset(downloadDir, stock, d.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", d.Name, err)
+		}
+		meta := RecordMeta{StockID: stock, Dataset: d.Name, Source: d.Source, ScrapedAt: time.Now()}
+		if path, err := datasetPath(downloadDir, stock, d.Name); err == nil {
+			if info, err := os.Stat(path); err == nil {
+				meta.ScrapedAt = info.ModTime()
+			}
+		}
+		for _, record := range d.Records(data) {
+			if err := w.Write(meta, d, record); err != nil {
+				return err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONSinkRoundTrip(t *testing.T) {
+	data := [][]string{
+		{"24W02", "590", "+10", "+1.72", "-", "18.3"},
+		{"24W01", "580", "-5", "-0.85", "32.3", "17.9"},
+		{"23W52", "570", "n/a", "0", "32.3", "17.6"},
+	}
+	want := [][]string{
+		{"24W02", "590", "10", "1.72", "-", "18.3"},
+		{"24W01", "580", "-5", "-0.85", "32.3", "17.9"},
+		{"23W52", "570", "n/a", "0", "32.3", "17.6"},
+	}
+
+	for _, ndjson := range []bool{false, true} {
+		dir := t.TempDir()
+		sink := NewJSONSink(dir, ndjson)
+		if err := sink.Save("2330", "per", data); err != nil {
+			t.Fatalf("Save(ndjson=%v) returned error: %v", ndjson, err)
+		}
+
+		got, err := ReadDataset(dir, "2330", "per")
+		if err != nil {
+			t.Fatalf("ReadDataset(ndjson=%v) returned error: %v", ndjson, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadDataset(ndjson=%v) got = %v, want %v", ndjson, got, want)
+		}
+	}
+}
+
+func TestJSONRecordWriterNDJSON(t *testing.T) {
+	dir := t.TempDir()
+	d, _ := LookupDataset("per")
+	path := filepath.Join(dir, "per.ndjson")
+	if err := WriteJSON(path, "2330", d, [][]string{{"24W02", "590", "+10", "+1.72", "-", "18.3"}}, true); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSpace(string(raw))
+	prefix := `{"stock_id":"2330","dataset":"per","period":"24W02","close":590,"change":10,"change_pct":1.72,"river_eps":null,"per":18.3,"source":"https://goodinfo.tw/tw/ShowK_ChartFlow.asp?RPT_CAT=PER","scraped_at":`
+	if !strings.HasPrefix(line, prefix) {
+		t.Errorf("unexpected NDJSON record:\n%s\nwant prefix:\n%s", line, prefix)
+	}
+	if strings.Count(string(raw), "\n") != 1 {
+		t.Errorf("expected one line per record, got %q", raw)
+	}
+}
@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// tableColumn is one column of an exported or Parquet table.
+type tableColumn struct {
+	Name string
+	Type ColumnType
+}
+
+// datasetColumns returns the typed columns of a dataset, optionally
+// prefixing every column name.
+func datasetColumns(d Dataset, prefix string) []tableColumn {
+	columns := make([]tableColumn, len(d.Columns))
+	for i, col := range d.Columns {
+		columns[i] = tableColumn{Name: prefix + col.Key, Type: col.Type}
+	}
+	return columns
+}
+
+// parquetTableWriter streams string rows into a snappy-compressed Parquet
+// file, converting each cell to the column's type. All columns are optional;
+// "-", empty and unparsable cells become nulls. Parquet groups order their
+// fields by name, so the file's column order is alphabetical.
+type parquetTableWriter struct {
+	file    *os.File
+	writer  *parquet.GenericWriter[any]
+	columns []tableColumn
+	indices []int
+}
+
+func newParquetTableWriter(path string, columns []tableColumn) (*parquetTableWriter, error) {
+	group := parquet.Group{}
+	for _, col := range columns {
+		switch col.Type {
+		case NumberColumn:
+			group[col.Name] = parquet.Optional(parquet.Leaf(parquet.DoubleType))
+		default:
+			group[col.Name] = parquet.Optional(parquet.String())
+		}
+	}
+	schema := parquet.NewSchema("table", group)
+
+	indices := make([]int, len(columns))
+	for i, col := range columns {
+		leaf, ok := schema.Lookup(col.Name)
+		if !ok {
+			return nil, fmt.Errorf("column %s missing from parquet schema", col.Name)
+		}
+		indices[i] = leaf.ColumnIndex
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writer := parquet.NewGenericWriter[any](file, schema, parquet.Compression(&parquet.Snappy))
+	return &parquetTableWriter{file: file, writer: writer, columns: columns, indices: indices}, nil
+}
+
+func (p *parquetTableWriter) WriteRow(row []string) error {
+	values := make(parquet.Row, len(p.indices))
+	for i, idx := range p.indices {
+		v := "-"
+		if i < len(row) {
+			v = row[i]
+		}
+		values[idx] = parquet.NullValue().Level(0, 0, idx)
+		switch p.columns[i].Type {
+		case NumberColumn:
+			if isBlank(v) {
+				break
+			}
+			n, ok := parseNumber(v)
+			if !ok {
+				return fmt.Errorf("column %s: %q is not a number", p.columns[i].Name, v)
+			}
+			values[idx] = parquet.ValueOf(n).Level(0, 1, idx)
+		default:
+			if !isBlank(v) {
+				values[idx] = parquet.ValueOf(v).Level(0, 1, idx)
+			}
+		}
+	}
+	_, err := p.writer.WriteRows([]parquet.Row{values})
+	return err
+}
+
+func (p *parquetTableWriter) Close() error {
+	if err := p.writer.Close(); err != nil {
+		p.file.Close()
+		return err
+	}
+	return p.file.Close()
+}
+
+// WriteParquet writes the dataset's records to a typed Parquet file. A cell
+// of a number column that is neither a number nor "-" is an error, as the
+// column cannot hold it.
+func WriteParquet(filePath string, d Dataset, data [][]string) error {
+	w, err := newParquetTableWriter(filePath, datasetColumns(d, ""))
+	if err != nil {
+		return err
+	}
+	for _, record := range d.Records(data) {
+		if err := w.WriteRow(record); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
+
+// ReadParquet reads a file written by WriteParquet back into string rows in
+// the dataset's column order. Nulls are returned as "-".
+func ReadParquet(filePath string, d Dataset) ([][]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+
+	schema := reader.Schema()
+	indices := make([]int, len(d.Columns))
+	for i, col := range d.Columns {
+		leaf, ok := schema.Lookup(col.Key)
+		if !ok {
+			return nil, fmt.Errorf("column %s missing from %s", col.Key, filePath)
+		}
+		indices[i] = leaf.ColumnIndex
+	}
+
+	var data [][]string
+	rows := make([]parquet.Row, 64)
+	for {
+		n, err := reader.ReadRows(rows)
+		for _, row := range rows[:n] {
+			record := make([]string, len(d.Columns))
+			for i, idx := range indices {
+				v := row[idx]
+				switch {
+				case v.IsNull():
+					record[i] = "-"
+				case d.Columns[i].Type == NumberColumn:
+					record[i] = strconv.FormatFloat(v.Double(), 'f', -1, 64)
+				default:
+					record[i] = string(v.ByteArray())
+				}
+			}
+			data = append(data, record)
+		}
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// ParquetSink writes one Parquet file per stock and dataset, partitioned
+// Hive-style as Dir/dataset=<dataset>/stock_id=<stock>/part-0.parquet.
+type ParquetSink struct {
+	Dir string
+}
+
+// NewParquetSink returns a ParquetSink rooted at dir.
+func NewParquetSink(dir string) *ParquetSink {
+	return &ParquetSink{Dir: dir}
+}
+
+func parquetPartitionPath(dir, stock, dataset string) string {
+	return filepath.Join(dir, "dataset="+dataset, "stock_id="+stock, "part-0.parquet")
+}
+
+func (p *ParquetSink) IsUpToDate(stock, dataset string, maxAge time.Duration) bool {
+	return IsFileUpToDate(parquetPartitionPath(p.Dir, stock, dataset), maxAge)
+}
+
+func (p *ParquetSink) LastSaved(stock, dataset string) (time.Time, bool) {
+	return fileModTime(parquetPartitionPath(p.Dir, stock, dataset))
+}
+
+func (p *ParquetSink) Save(stock, dataset string, data [][]string) error {
+	d, err := LookupDataset(dataset)
+	if err != nil {
+		return err
+	}
+	return WriteParquet(parquetPartitionPath(p.Dir, stock, dataset), d, data)
+}
+
+// combineStockParquet writes a stock's datasets side by side into one typed
+// Parquet file, mirroring the combined CSV layout. Columns are prefixed with
+// their dataset name, and row i holds the i-th record of every dataset.
+func combineStockParquet(downloadDir, stock, finalOutput string) error {
+	var (
+		columns []tableColumn
+		tables  [][][]string
+		maxRows int
+	)
+	for _, d := range Datasets {
+		data, err := ReadDataset(downloadDir, stock, d.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", d.Name, err)
+		}
+		records := d.Records(data)
+		columns = append(columns, datasetColumns(d, d.Name+"_")...)
+		tables = append(tables, records)
+		maxRows = max(maxRows, len(records))
+	}
+
+	w, err := newParquetTableWriter(finalOutput, columns)
+	if err != nil {
+		return err
+	}
+	for i := range maxRows {
+		var row []string
+		for t, records := range tables {
+			if i < len(records) {
+				row = append(row, records[i]...)
+			} else {
+				for range Datasets[t].Columns {
+					row = append(row, "-")
+				}
+			}
+		}
+		if err := w.WriteRow(row); err != nil {
+			w.Close()
+			return err
+		}
+	}
+	return w.Close()
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParquetSinkRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewParquetSink(dir)
+
+	data := [][]string{
+		{"交易週別", "收盤價", "漲跌價", "漲跌幅", "河流圖 EPS(元)", "目前 PER (倍)"},
+		{"24W02", "1,090", "+10", "+1.72%", "-", "18.3"},
+	}
+	if err := sink.Save("2330", "per", data); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	path := filepath.Join(dir, "dataset=per", "stock_id=2330", "part-0.parquet")
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected partitioned file at %s: %v", path, err)
+	}
+	if !sink.IsUpToDate("2330", "per", 0) {
+		t.Fatalf("expected freshly written partition to be up to date")
+	}
+
+	got, err := ReadDataset(dir, "2330", "per")
+	if err != nil {
+		t.Fatalf("ReadDataset returned error: %v", err)
+	}
+	want := [][]string{{"24W02", "1090", "10", "1.72", "-", "18.3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDataset() got = %v, want %v", got, want)
+	}
+}
+
+func TestParquetSinkRejectsNonNumbers(t *testing.T) {
+	sink := NewParquetSink(t.TempDir())
+	data := [][]string{{"24W02", "1,090", "n/a", "+1.72%", "-", "18.3"}}
+	err := sink.Save("2330", "per", data)
+	if err == nil || !strings.Contains(err.Error(), `"n/a"`) {
+		t.Fatalf("Save() error = %v, want one naming the unparsable cell", err)
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   float64
+		wantOK bool
+	}{
+		{"1,234.5", 1234.5, true},
+		{"+10", 10, true},
+		{"-0.85", -0.85, true},
+		{"12.3%", 12.3, true},
+		{"-", 0, false},
+		{"", 0, false},
+		{"N/A", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseNumber(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseNumber(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Sink persists the table scraped for one stock and dataset.
+type Sink interface {
+	// IsUpToDate reports whether the stored data is fresh enough to skip
+	// scraping, using IsFresh with maxAge.
+	IsUpToDate(stock, dataset string, maxAge time.Duration) bool
+	// LastSaved returns when the data was last stored; false when it never was.
+	LastSaved(stock, dataset string) (time.Time, bool)
+	// Save stores the scraped rows, replacing any previous copy.
+	Save(stock, dataset string, data [][]string) error
+}
+
+// CSVSink writes one CSV per stock and dataset under Dir/<stock>/<dataset>.csv.
+type CSVSink struct {
+	Dir string
+}
+
+// NewCSVSink returns a CSVSink rooted at dir.
+func NewCSVSink(dir string) *CSVSink {
+	return &CSVSink{Dir: dir}
+}
+
+func (c *CSVSink) path(stock, dataset string) string {
+	return filepath.Join(c.Dir, stock, dataset+".csv")
+}
+
+func (c *CSVSink) IsUpToDate(stock, dataset string, maxAge time.Duration) bool {
+	return IsFileUpToDate(c.path(stock, dataset), maxAge)
+}
+
+func (c *CSVSink) LastSaved(stock, dataset string) (time.Time, bool) {
+	return fileModTime(c.path(stock, dataset))
+}
+
+func (c *CSVSink) Save(stock, dataset string, data [][]string) error {
+	if err := os.MkdirAll(filepath.Join(c.Dir, stock), 0o755); err != nil {
+		return err
+	}
+	return WriteCSV(c.path(stock, dataset), data)
+}
+
+// FileFormats lists the raw download formats NewFileSink accepts.
+var FileFormats = []string{"csv", "parquet", "json", "ndjson"}
+
+// CombinedFormats lists the formats CombineSuccessfulStocks can write: every
+// file format plus an XLSX workbook.
+var CombinedFormats = append(slices.Clone(FileFormats), "xlsx")
+
+// NewFileSink returns the sink writing raw downloads under dir in the given format.
+func NewFileSink(dir, format string) (Sink, error) {
+	switch format {
+	case "csv":
+		return NewCSVSink(dir), nil
+	case "parquet":
+		return NewParquetSink(dir), nil
+	case "json":
+		return NewJSONSink(dir, false), nil
+	case "ndjson":
+		return NewJSONSink(dir, true), nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// datasetPath locates a stock's raw dataset file in downloadDir, whichever
+// file sink wrote it. When files of several formats exist, left behind by
+// runs before -format changed, the most recently written one wins.
+func datasetPath(downloadDir, stock, dataset string) (string, error) {
+	candidates := []string{
+		filepath.Join(downloadDir, stock, dataset+".csv"),
+		filepath.Join(downloadDir, stock, dataset+".ndjson"),
+		filepath.Join(downloadDir, stock, dataset+".json"),
+		parquetPartitionPath(downloadDir, stock, dataset),
+	}
+	var (
+		newest  string
+		newestT time.Time
+	)
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if newest == "" || info.ModTime().After(newestT) {
+			newest, newestT = path, info.ModTime()
+		}
+	}
+	if newest == "" {
+		return "", fmt.Errorf("no %s data for stock %s: %w", dataset, stock, os.ErrNotExist)
+	}
+	return newest, nil
+}
+
+// DatasetModTime returns when a stock's raw dataset was last written to downloadDir.
+func DatasetModTime(downloadDir, stock, dataset string) (time.Time, error) {
+	path, err := datasetPath(downloadDir, stock, dataset)
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// ReadDataset reads a stock's raw dataset from downloadDir, whichever file
+// sink saved it.
+func ReadDataset(downloadDir, stock, dataset string) ([][]string, error) {
+	path, err := datasetPath(downloadDir, stock, dataset)
+	if err != nil {
+		return nil, err
+	}
+	d, err := LookupDataset(dataset)
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".csv":
+		return ReadCSV(path)
+	case ".json", ".ndjson":
+		return ReadJSON(path, d)
+	default:
+		return ReadParquet(path, d)
+	}
+}
+
+// MultiSink fans writes out to several sinks. Data is only considered up to
+// date when every sink has a fresh copy.
+type MultiSink []Sink
+
+func (m MultiSink) IsUpToDate(stock, dataset string, maxAge time.Duration) bool {
+	for _, s := range m {
+		if !s.IsUpToDate(stock, dataset, maxAge) {
+			return false
+		}
+	}
+	return len(m) > 0
+}
+
+// LastSaved returns the oldest save time among the sinks, as the data is
+// only as fresh as its stalest copy.
+func (m MultiSink) LastSaved(stock, dataset string) (time.Time, bool) {
+	var oldest time.Time
+	for _, s := range m {
+		t, ok := s.LastSaved(stock, dataset)
+		if !ok {
+			return time.Time{}, false
+		}
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+	}
+	return oldest, len(m) > 0
+}
+
+func (m MultiSink) Save(stock, dataset string, data [][]string) error {
+	var errs []error
+	for _, s := range m {
+		if err := s.Save(stock, dataset, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMultiSinkLastSaved(t *testing.T) {
+	dir := t.TempDir()
+	csvSink := NewCSVSink(filepath.Join(dir, "csv"))
+	jsonSink := NewJSONSink(filepath.Join(dir, "json"), false)
+	multi := MultiSink{csvSink, jsonSink}
+
+	if _, ok := multi.LastSaved("2330", "per"); ok {
+		t.Fatalf("expected no save time before saving")
+	}
+	data := [][]string{{"24W01", "580", "0", "0", "1", "2"}}
+	if err := multi.Save("2330", "per", data); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	old := time.Now().Add(-72 * time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(csvSink.path("2330", "per"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := multi.LastSaved("2330", "per")
+	if !ok || !got.Equal(old) {
+		t.Fatalf("LastSaved() = %v, %v; want the older copy %v", got, ok, old)
+	}
+	if multi.IsUpToDate("2330", "per", 24*time.Hour) {
+		t.Fatalf("expected the stale copy to make the data out of date")
+	}
+	if !jsonSink.IsUpToDate("2330", "per", 24*time.Hour) {
+		t.Fatalf("expected the JSON copy to be up to date")
+	}
+}
+
+func TestReadDatasetPrefersNewestFormat(t *testing.T) {
+	dir := t.TempDir()
+	stale := [][]string{{"24W01", "500", "0", "0", "1", "2"}}
+	fresh := [][]string{{"24W01", "580", "0", "0", "1", "2"}}
+	if err := NewCSVSink(dir).Save("2330", "per", stale); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-72 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "2330", "per.csv"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewJSONSink(dir, true).Save("2330", "per", fresh); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadDataset(dir, "2330", "per")
+	if err != nil {
+		t.Fatalf("ReadDataset returned error: %v", err)
+	}
+	if len(got) == 0 || got[len(got)-1][1] != "580" {
+		t.Errorf("ReadDataset() = %q, want the newer NDJSON copy", got)
+	}
+}
@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteTimeLayout = time.RFC3339
+
+// SQLiteStore persists scraped tables into a single SQLite database with one
+// table per dataset keyed by (stock_id, period). Re-scraping a stock upserts
+// its rows, and every run is recorded in the runs, run_stocks and scrapes
+// metadata tables.
+type SQLiteStore struct {
+	db    *sql.DB
+	runID int64
+}
+
+// OpenSQLite opens (or creates) the database at path and ensures the schema exists.
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	// SQLite allows a single writer; serialise access instead of retrying on SQLITE_BUSY.
+	db.SetMaxOpenConns(1)
+
+	store := &SQLiteStore{db: db}
+	if err := store.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return store, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			started_at TEXT NOT NULL,
+			finished_at TEXT,
+			start_date TEXT,
+			end_date TEXT,
+			workers INTEGER,
+			stock_count INTEGER,
+			success_count INTEGER,
+			failed_count INTEGER
+		)`,
+		`CREATE TABLE IF NOT EXISTS run_stocks (
+			run_id INTEGER NOT NULL,
+			stock_id TEXT NOT NULL,
+			status TEXT NOT NULL,
+			PRIMARY KEY (run_id, stock_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS scrapes (
+			run_id INTEGER,
+			stock_id TEXT NOT NULL,
+			dataset TEXT NOT NULL,
+			row_count INTEGER NOT NULL,
+			scraped_at TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS scrapes_stock_dataset ON scrapes (stock_id, dataset, scraped_at)`,
+	}
+	for _, d := range Datasets {
+		statements = append(statements, createDatasetTable(d))
+	}
+	for _, stmt := range statements {
+		if _, err := s.db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate sqlite schema: %w", err)
+		}
+	}
+	return nil
+}
+
+func createDatasetTable(d Dataset) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %q (\n\tstock_id TEXT NOT NULL", d.Name)
+	for _, col := range d.Columns {
+		fmt.Fprintf(&b, ",\n\t%q TEXT", col.Key)
+	}
+	b.WriteString(",\n\trun_id INTEGER,\n\tupdated_at TEXT NOT NULL")
+	fmt.Fprintf(&b, ",\n\tPRIMARY KEY (stock_id, %q)\n)", d.Columns[0].Key)
+	return b.String()
+}
+
+func upsertDatasetRow(d Dataset) string {
+	cols := []string{"stock_id"}
+	var updates []string
+	for _, col := range d.Columns {
+		cols = append(cols, fmt.Sprintf("%q", col.Key))
+	}
+	cols = append(cols, "run_id", "updated_at")
+	for _, col := range cols[2:] {
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", col, col))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+	return fmt.Sprintf(
+		"INSERT INTO %q (%s) VALUES (%s) ON CONFLICT (stock_id, %q) DO UPDATE SET %s",
+		d.Name,
+		strings.Join(cols, ", "),
+		placeholders,
+		d.Columns[0].Key,
+		strings.Join(updates, ", "),
+	)
+}
+
+// Close closes the underlying database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// BeginRun records the start of a scrape run. Rows saved afterwards are tagged
+// with the new run id.
+func (s *SQLiteStore) BeginRun(startDate, endDate string, workers, stockCount int) error {
+	res, err := s.db.Exec(
+		`INSERT INTO runs (started_at, start_date, end_date, workers, stock_count) VALUES (?, ?, ?, ?, ?)`,
+		time.Now().Format(sqliteTimeLayout),
+		startDate,
+		endDate,
+		workers,
+		stockCount,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+	s.runID, err = res.LastInsertId()
+	return err
+}
+
+// FinishRun stores the per-stock outcome of the current run and its finish time.
+func (s *SQLiteStore) FinishRun(successStocks, errorStocks []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for status, stocks := range map[string][]string{"success": successStocks, "failed": errorStocks} {
+		for _, stock := range stocks {
+			if _, err := tx.Exec(
+				`INSERT OR REPLACE INTO run_stocks (run_id, stock_id, status) VALUES (?, ?, ?)`,
+				s.runID,
+				stock,
+				status,
+			); err != nil {
+				return fmt.Errorf("failed to record stock status: %w", err)
+			}
+		}
+	}
+	if _, err := tx.Exec(
+		`UPDATE runs SET finished_at = ?, success_count = ?, failed_count = ? WHERE id = ?`,
+		time.Now().Format(sqliteTimeLayout),
+		len(successStocks),
+		len(errorStocks),
+		s.runID,
+	); err != nil {
+		return fmt.Errorf("failed to finish run: %w", err)
+	}
+	return tx.Commit()
+}
+
+// IsUpToDate reports whether the stock's dataset was last saved within maxAge
+// (today when maxAge is zero).
+func (s *SQLiteStore) IsUpToDate(stock, dataset string, maxAge time.Duration) bool {
+	t, ok := s.LastSaved(stock, dataset)
+	return ok && IsFresh(t, maxAge)
+}
+
+// LastSaved returns the time of the stock's latest scrape of dataset.
+func (s *SQLiteStore) LastSaved(stock, dataset string) (time.Time, bool) {
+	var scrapedAt sql.NullString
+	err := s.db.QueryRow(
+		`SELECT MAX(scraped_at) FROM scrapes WHERE stock_id = ? AND dataset = ?`,
+		stock,
+		dataset,
+	).Scan(&scrapedAt)
+	if err != nil || !scrapedAt.Valid {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(sqliteTimeLayout, scrapedAt.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Save replaces the rows of one stock in the dataset's table with the
+// scraped ones, like the file sinks replace the whole dataset, so periods
+// that are no longer listed do not linger.
+func (s *SQLiteStore) Save(stock, dataset string, data [][]string) error {
+	d, err := LookupDataset(dataset)
+	if err != nil {
+		return err
+	}
+	records := d.Records(data)
+	now := time.Now().Format(sqliteTimeLayout)
+	var runID any
+	if s.runID != 0 {
+		runID = s.runID
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %q WHERE stock_id = ?`, d.Name), stock); err != nil {
+		return fmt.Errorf("failed to clear %s rows: %w", dataset, err)
+	}
+	// An upsert still, as a page can list the same period twice.
+	stmt, err := tx.Prepare(upsertDatasetRow(d))
+	if err != nil {
+		return fmt.Errorf("failed to prepare upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		args := make([]any, 0, len(record)+3)
+		args = append(args, stock)
+		for _, v := range record {
+			args = append(args, v)
+		}
+		args = append(args, runID, now)
+		if _, err := stmt.Exec(args...); err != nil {
+			return fmt.Errorf("failed to upsert %s row %s: %w", dataset, record[0], err)
+		}
+	}
+
+	if _, err := tx.Exec(
+		`INSERT INTO scrapes (run_id, stock_id, dataset, row_count, scraped_at) VALUES (?, ?, ?, ?, ?)`,
+		runID,
+		stock,
+		dataset,
+		len(records),
+		now,
+	); err != nil {
+		return fmt.Errorf("failed to record scrape: %w", err)
+	}
+	return tx.Commit()
+}
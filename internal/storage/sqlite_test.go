@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStoreSave(t *testing.T) {
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "scraper.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite returned error: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.BeginRun("2024-01-01", "2024-12-31", 5, 1); err != nil {
+		t.Fatalf("BeginRun returned error: %v", err)
+	}
+
+	if store.IsUpToDate("2330", "per", 0) {
+		t.Fatalf("expected empty store to not be up to date")
+	}
+
+	first := [][]string{
+		{"24W02", "590", "+10", "+1.72", "32.3", "18.3"},
+		{"24W01", "580", "-5", "-0.85", "32.3", "17.9"},
+	}
+	if err := store.Save("2330", "per", first); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	// Re-scraping restates 24W01, adds 24W03 and no longer lists 24W02, which
+	// must be dropped like the file sinks drop it.
+	second := [][]string{
+		{"交易週別", "收盤價", "漲跌價", "漲跌幅", "河流圖 EPS(元)", "目前 PER (倍)"},
+		{"24W03", "600", "+10", "+1.69", "32.3", "18.6"},
+		{"24W01", "581", "-4", "-0.68", "32.3", "17.9"},
+	}
+	if err := store.Save("2330", "per", second); err != nil {
+		t.Fatalf("Save returned error: %v", err)
+	}
+
+	var count int
+	if err := store.db.QueryRow(`SELECT COUNT(*) FROM per WHERE stock_id = '2330'`).Scan(&count); err != nil {
+		t.Fatalf("count query failed: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rows after re-save, got %d", count)
+	}
+	var dropped int
+	if err := store.db.QueryRow(
+		`SELECT COUNT(*) FROM per WHERE stock_id = '2330' AND period = '24W02'`,
+	).Scan(&dropped); err != nil {
+		t.Fatalf("dropped query failed: %v", err)
+	}
+	if dropped != 0 {
+		t.Fatalf("expected 24W02 to be dropped, got %d row(s)", dropped)
+	}
+
+	var closePrice string
+	if err := store.db.QueryRow(
+		`SELECT close FROM per WHERE stock_id = '2330' AND period = '24W01'`,
+	).Scan(&closePrice); err != nil {
+		t.Fatalf("close query failed: %v", err)
+	}
+	if closePrice != "581" {
+		t.Fatalf("expected restated close 581, got %s", closePrice)
+	}
+
+	if !store.IsUpToDate("2330", "per", 0) {
+		t.Fatalf("expected stock to be up to date after save")
+	}
+
+	if err := store.FinishRun([]string{"2330"}, nil); err != nil {
+		t.Fatalf("FinishRun returned error: %v", err)
+	}
+	var status string
+	if err := store.db.QueryRow(
+		`SELECT status FROM run_stocks WHERE stock_id = '2330'`,
+	).Scan(&status); err != nil {
+		t.Fatalf("status query failed: %v", err)
+	}
+	if status != "success" {
+		t.Fatalf("expected success status, got %s", status)
+	}
+}
+
+func TestSQLiteStoreUnknownDataset(t *testing.T) {
+	store, err := OpenSQLite(filepath.Join(t.TempDir(), "scraper.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite returned error: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Save("2330", "unknown", [][]string{{"a"}}); err == nil {
+		t.Fatalf("expected error for unknown dataset")
+	}
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity grades an Issue.
+type Severity string
+
+const (
+	// SeverityError marks data that is wrong: missing, duplicated, out of
+	// order or unparsable.
+	SeverityError Severity = "error"
+	// SeverityWarning marks data that is suspicious but can be genuine, such
+	// as gaps over holidays or jumps after a share split.
+	SeverityWarning Severity = "warning"
+)
+
+// Issue is a problem found in one stock's downloaded data.
+type Issue struct {
+	Stock    string
+	Dataset  string
+	Severity Severity
+	Message  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s (%s) %s: %s", i.Stock, i.Dataset, i.Severity, i.Message)
+}
+
+// maxJump is the largest period-over-period change of a closing price, as a
+// factor either way, that is not reported as implausible.
+const maxJump = 10
+
+// priceColumns are the column keys holding share prices, which can never be
+// negative.
+var priceColumns = []string{"open", "high", "low", "close", "prev_close"}
+
+// ValidateStock checks the given datasets of a stock (default: all) in
+// downloadDir. A dataset must exist, be readable and hold at least one
+// record; its records are then checked by CheckRecords.
+func ValidateStock(downloadDir, stock string, datasets ...string) []Issue {
+	if len(datasets) == 0 {
+		datasets = DatasetNames()
+	}
+	var issues []Issue
+	for _, name := range datasets {
+		d, err := LookupDataset(name)
+		if err != nil {
+			issues = append(issues, Issue{stock, name, SeverityError, err.Error()})
+			continue
+		}
+		data, err := ReadDataset(downloadDir, stock, d.Name)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			issues = append(issues, Issue{stock, d.Name, SeverityError, "missing"})
+		case err != nil:
+			issues = append(issues, Issue{stock, d.Name, SeverityError, fmt.Sprintf("unreadable: %v", err)})
+		default:
+			records := d.Records(data)
+			if len(records) == 0 {
+				issues = append(issues, Issue{stock, d.Name, SeverityError, "no records"})
+				continue
+			}
+			issues = append(issues, CheckRecords(stock, d, records)...)
+		}
+	}
+	return issues
+}
+
+// period is a parsed period cell. Index counts periods of the same kind, so
+// consecutive periods differ by one.
+type period struct {
+	Label string
+	Kind  byte // 'W'eek, 'M'onth or 'Q'uarter
+	Index int
+}
+
+// periodPattern matches goodinfo.tw periods such as "24W02", "2024/01",
+// "24M01" and "2024Q1".
+var periodPattern = regexp.MustCompile(`^(\d{2}|\d{4})([WMQ/])(\d{1,2})$`)
+
+// centuryPivot is the last two-digit year read as 20xx; later ones are 19xx,
+// since goodinfo.tw history reaches back into the 1990s.
+const centuryPivot = 50
+
+// expandYear turns a two-digit year into a four-digit one.
+func expandYear(yy int) int {
+	if yy > centuryPivot {
+		return 1900 + yy
+	}
+	return 2000 + yy
+}
+
+func parsePeriod(s string) (period, bool) {
+	m := periodPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return period{}, false
+	}
+	year, _ := strconv.Atoi(m[1])
+	if len(m[1]) == 2 {
+		year = expandYear(year)
+	}
+	n, _ := strconv.Atoi(m[3])
+	p := period{Label: s, Kind: m[2][0]}
+	switch p.Kind {
+	case 'W':
+		if n < 1 || n > 53 {
+			return period{}, false
+		}
+		// ISO week 1 is the week holding January 4th; index weeks by the
+		// number of Mondays since the Unix epoch (January 5th, 1970).
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+		monday := jan4.AddDate(0, 0, (n-1)*7-(int(jan4.Weekday())+6)%7)
+		p.Index = int(monday.Unix()/86400-4) / 7
+	case 'M', '/':
+		if n < 1 || n > 12 {
+			return period{}, false
+		}
+		p.Kind = 'M'
+		p.Index = year*12 + n - 1
+	case 'Q':
+		if n < 1 || n > 4 {
+			return period{}, false
+		}
+		p.Index = year*4 + n - 1
+	}
+	return p, true
+}
+
+// CheckRecords checks the records of one stock's dataset, in file order, for
+// unrecognized, duplicate and out-of-order periods, gaps in the calendar,
+// rows without values, unparsable numbers, negative prices and closing
+// prices jumping more than tenfold between consecutive periods.
+func CheckRecords(stock string, d Dataset, records [][]string) []Issue {
+	var issues []Issue
+	add := func(severity Severity, format string, args ...any) {
+		issues = append(issues, Issue{stock, d.Name, severity, fmt.Sprintf(format, args...)})
+	}
+
+	var (
+		periods    []period
+		unknown    []string
+		duplicates []string
+		seen       = make(map[int]bool)
+	)
+	for _, record := range records {
+		p, ok := parsePeriod(record[0])
+		if !ok || (len(periods) > 0 && p.Kind != periods[0].Kind) {
+			unknown = append(unknown, record[0])
+			continue
+		}
+		if seen[p.Index] {
+			duplicates = append(duplicates, p.Label)
+		}
+		seen[p.Index] = true
+		periods = append(periods, p)
+	}
+	if len(unknown) > 0 {
+		add(SeverityError, "%d unrecognized period(s): %s", len(unknown), summarize(unknown))
+	}
+	if len(duplicates) > 0 {
+		add(SeverityError, "%d duplicate period(s): %s", len(duplicates), summarize(duplicates))
+	}
+
+	// goodinfo.tw lists the newest period first, but either direction is
+	// fine as long as it is kept throughout.
+	direction, disorder := 0, 0
+	var firstDisorder string
+	for i := 1; i < len(periods); i++ {
+		diff := periods[i].Index - periods[i-1].Index
+		if diff == 0 {
+			continue
+		}
+		step := 1
+		if diff < 0 {
+			step = -1
+		}
+		if direction == 0 {
+			direction = step
+		} else if step != direction {
+			if disorder == 0 {
+				firstDisorder = fmt.Sprintf("%s after %s", periods[i].Label, periods[i-1].Label)
+			}
+			disorder++
+		}
+	}
+	if disorder > 0 {
+		add(SeverityError, "periods out of order %d time(s), first %s", disorder, firstDisorder)
+	}
+
+	chrono := slices.Clone(periods)
+	slices.SortStableFunc(chrono, func(a, b period) int { return a.Index - b.Index })
+	var (
+		gaps, missing, largest int
+		largestGap             string
+	)
+	for i := 1; i < len(chrono); i++ {
+		n := chrono[i].Index - chrono[i-1].Index - 1
+		if n <= 0 {
+			continue
+		}
+		gaps++
+		missing += n
+		if n > largest {
+			largest = n
+			largestGap = fmt.Sprintf("%s and %s", chrono[i-1].Label, chrono[i].Label)
+		}
+	}
+	if gaps > 0 {
+		add(SeverityWarning, "%d missing period(s) in %d gap(s), largest %d between %s",
+			missing, gaps, largest, largestGap)
+	}
+
+	var empty []string
+	for _, record := range records {
+		blank := true
+		for i, col := range d.Columns {
+			if col.Type == NumberColumn && !isBlank(record[i]) {
+				blank = false
+				break
+			}
+		}
+		if blank {
+			empty = append(empty, record[0])
+		}
+	}
+	if len(empty) > 0 {
+		add(SeverityError, "%d row(s) without values: %s", len(empty), summarize(empty))
+	}
+
+	for i, col := range d.Columns {
+		if col.Type != NumberColumn {
+			continue
+		}
+		var unparsable, negative []string
+		for _, record := range records {
+			v := record[i]
+			n, ok := parseNumber(v)
+			switch {
+			case !ok && !isBlank(v):
+				unparsable = append(unparsable, fmt.Sprintf("%q at %s", v, record[0]))
+			case ok && n < 0 && slices.Contains(priceColumns, col.Key):
+				negative = append(negative, fmt.Sprintf("%s at %s", v, record[0]))
+			}
+		}
+		if len(unparsable) > 0 {
+			add(SeverityError, "%s: %d unparsable number(s): %s", col.Key, len(unparsable), summarize(unparsable))
+		}
+		if len(negative) > 0 {
+			add(SeverityError, "%s: %d negative price(s): %s", col.Key, len(negative), summarize(negative))
+		}
+	}
+
+	if c := slices.IndexFunc(d.Columns, func(col Column) bool { return col.Key == "close" }); c >= 0 {
+		closes := make(map[string]string, len(records))
+		for _, record := range records {
+			closes[record[0]] = record[c]
+		}
+		// Compare each close with the last valid one, skipping blank periods.
+		var (
+			jumps []string
+			last  string
+			prev  float64
+		)
+		for _, p := range chrono {
+			cur, ok := parseNumber(closes[p.Label])
+			if !ok || cur <= 0 {
+				continue
+			}
+			if prev > 0 && (cur/prev > maxJump || prev/cur > maxJump) {
+				jumps = append(jumps, fmt.Sprintf("%s %s to %s %s", last, closes[last], p.Label, closes[p.Label]))
+			}
+			last, prev = p.Label, cur
+		}
+		if len(jumps) > 0 {
+			add(SeverityWarning, "close: %d jump(s) over %dx: %s", len(jumps), maxJump, summarize(jumps))
+		}
+	}
+	return issues
+}
+
+// summarize joins the first few items of a list for an issue message.
+func summarize(items []string) string {
+	const limit = 3
+	if len(items) <= limit {
+		return strings.Join(items, ", ")
+	}
+	return strings.Join(items[:limit], ", ") + fmt.Sprintf(", ... (%d more)", len(items)-limit)
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateStock(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewCSVSink(dir)
+	for _, d := range Datasets {
+		row := []string{"24W01"}
+		for range d.Columns[1:] {
+			row = append(row, "1")
+		}
+		switch d.Name {
+		case "cashflow":
+			continue
+		case "equity":
+			row = []string{"週別"}
+		}
+		if err := sink.Save("2330", d.Name, [][]string{row}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := ValidateStock(dir, "2330")
+	want := []Issue{
+		{"2330", "cashflow", SeverityError, "missing"},
+		{"2330", "equity", SeverityError, "no records"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateStock() got = %v, want %v", got, want)
+	}
+
+	if got := ValidateStock(dir, "2330", "per", "stockdata"); len(got) != 0 {
+		t.Errorf("ValidateStock(per, stockdata) got = %v, want no issues", got)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		a, b string
+		diff int
+	}{
+		{"24W01", "24W02", 1},
+		{"20W52", "20W53", 1},
+		{"20W53", "21W01", 1},
+		{"23W52", "24W01", 1},
+		{"2023/12", "2024/01", 1},
+		{"23M12", "2024/02", 2},
+		{"2023Q4", "24Q1", 1},
+		{"98W53", "99W01", 1},
+		{"99W52", "00W01", 1},
+		{"99M12", "2000/01", 1},
+		{"1999Q4", "00Q1", 1},
+	}
+	for _, tt := range tests {
+		a, okA := parsePeriod(tt.a)
+		b, okB := parsePeriod(tt.b)
+		if !okA || !okB || a.Kind != b.Kind || b.Index-a.Index != tt.diff {
+			t.Errorf("parsePeriod(%s, %s) = %+v, %+v, want %d apart", tt.a, tt.b, a, b, tt.diff)
+		}
+	}
+	for _, s := range []string{"", "24W54", "2024/13", "24Q5", "2024-01", "週別"} {
+		if p, ok := parsePeriod(s); ok {
+			t.Errorf("parsePeriod(%q) = %+v, want failure", s, p)
+		}
+	}
+}
+
+func TestCheckRecords(t *testing.T) {
+	per, _ := LookupDataset("per")
+	tests := []struct {
+		name    string
+		records [][]string
+		want    []string
+	}{
+		{"clean newest first", [][]string{
+			{"24W03", "600", "+10", "+1.7%", "32", "18"},
+			{"24W02", "590", "-", "-", "32", "18"},
+			{"24W01", "580", "0", "0", "32", "18"},
+		}, nil},
+		{"duplicate and unknown", [][]string{
+			{"24W02", "590", "0", "0", "1", "1"},
+			{"24W02", "590", "0", "0", "1", "1"},
+			{"24X01", "580", "0", "0", "1", "1"},
+		}, []string{
+			"error: 1 unrecognized period(s): 24X01",
+			"error: 1 duplicate period(s): 24W02",
+		}},
+		{"out of order and gap", [][]string{
+			{"24W06", "600", "0", "0", "1", "1"},
+			{"24W02", "590", "0", "0", "1", "1"},
+			{"24W03", "580", "0", "0", "1", "1"},
+		}, []string{
+			"error: periods out of order 1 time(s), first 24W03 after 24W02",
+			"warning: 2 missing period(s) in 1 gap(s), largest 2 between 24W03 and 24W06",
+		}},
+		{"bad values", [][]string{
+			{"24W04", "6000", "0", "0", "1", "1"},
+			{"24W03", "-", "-", "-", "-", "-"},
+			{"24W02", "-5", "n/a", "0", "1", "1"},
+			{"24W01", "580", "0", "0", "1", "1"},
+		}, []string{
+			"error: 1 row(s) without values: 24W03",
+			"error: close: 1 negative price(s): -5 at 24W02",
+			`error: change: 1 unparsable number(s): "n/a" at 24W02`,
+			"warning: close: 1 jump(s) over 10x: 24W01 580 to 24W04 6000",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range CheckRecords("2330", per, tt.records) {
+				got = append(got, strings.TrimPrefix(issue.String(), "2330 (per) "))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckRecords() got = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxSheet is one worksheet of a combined workbook.
+type xlsxSheet struct {
+	Name string
+	Rows [][]string
+}
+
+// sheetNames holds the worksheet titles of the combined workbook per header language.
+var sheetNames = map[string][3]string{
+	"zh":   {"股價", "營收與現金流", "股權分散"},
+	"en":   {"Prices", "Revenue and Cash Flow", "Equity Distribution"},
+	"both": {"股價 Prices", "營收與現金流 Revenue", "股權分散 Equity"},
+}
+
+// combineStockXLSX writes a stock's datasets into a workbook laid out like the
+// combined CSV but split over three sheets: PER next to weekly prices, monthly
+// revenue next to cash flow, and the equity distribution on its own.
+func combineStockXLSX(downloadDir, stock, finalOutput, lang string) error {
+	tables, err := loadHeadedTables(downloadDir, stock, lang)
+	if err != nil {
+		return err
+	}
+
+	prices, err := mergeCSVData(tables[0], tables[1])
+	if err != nil {
+		return fmt.Errorf("failed to merge per and stock data: %v", err)
+	}
+	revenue, err := mergeCSVData(tables[2], tables[3])
+	if err != nil {
+		return fmt.Errorf("failed to merge monthly revenue and cashflow data: %v", err)
+	}
+
+	names := sheetNames[lang]
+	return writeXLSX(finalOutput, []xlsxSheet{
+		{Name: names[0], Rows: prices},
+		{Name: names[1], Rows: revenue},
+		{Name: names[2], Rows: tables[4]},
+	})
+}
+
+// WriteDatasetXLSX writes one dataset's rows to a single-sheet workbook named
+// after the dataset.
+func WriteDatasetXLSX(filePath string, d Dataset, data [][]string) error {
+	return writeXLSX(filePath, []xlsxSheet{{Name: d.Name, Rows: data}})
+}
+
+// writeXLSX streams the sheets into a new workbook. Cells that parse as
+// numbers are stored as numbers so they can be charted and summed.
+func writeXLSX(filePath string, sheets []xlsxSheet) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sheet.Name); err != nil {
+				return err
+			}
+		} else if _, err := f.NewSheet(sheet.Name); err != nil {
+			return err
+		}
+
+		sw, err := f.NewStreamWriter(sheet.Name)
+		if err != nil {
+			return err
+		}
+		for r, row := range sheet.Rows {
+			values := make([]interface{}, len(row))
+			for c, cell := range row {
+				if n, ok := parseNumber(cell); ok {
+					values[c] = n
+				} else {
+					values[c] = cell
+				}
+			}
+			axis, err := excelize.CoordinatesToCellName(1, r+1)
+			if err != nil {
+				return err
+			}
+			if err := sw.SetRow(axis, values); err != nil {
+				return err
+			}
+		}
+		if err := sw.Flush(); err != nil {
+			return err
+		}
+	}
+	return f.SaveAs(filePath)
+}
@@ -0,0 +1,332 @@
+// Package universe maintains the list of companies traded on TWSE, TPEx and
+// the emerging stock board, fetched from the TWSE ISIN code pages.
+package universe
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ISINURL is the TWSE ISIN page listing every security of a market; the
+// market's strMode is appended.
+const ISINURL = "https://isin.twse.com.tw/isin/C_public.jsp?strMode="
+
+// Market is a board whose securities are listed on one ISIN page.
+type Market struct {
+	Name string
+	Mode int // strMode of the ISIN page
+}
+
+// Markets lists the supported markets: TWSE listed (上市), TPEx OTC (上櫃) and
+// the emerging stock board (興櫃).
+var Markets = []Market{
+	{"listed", 2},
+	{"otc", 4},
+	{"emerging", 5},
+}
+
+// MarketNames lists the accepted market names.
+func MarketNames() []string {
+	names := make([]string, len(Markets))
+	for i, m := range Markets {
+		names[i] = m.Name
+	}
+	return names
+}
+
+// Categories maps the English category names to the section headings of the
+// ISIN pages they match by prefix.
+var Categories = map[string]string{
+	"stock":     "股票",
+	"preferred": "特別股",
+	"etf":       "ETF",
+	"etn":       "ETN",
+	"tdr":       "臺灣存託憑證",
+	"reit":      "受益證券",
+}
+
+// Company is one security of the universe.
+type Company struct {
+	ID       string
+	Name     string
+	Market   string // listed, otc or emerging
+	Industry string
+	Category string // a key of Categories, or the raw section heading
+	ListedOn string // YYYY-MM-DD
+	ISIN     string
+}
+
+// csvHeader is the header row of a universe file.
+var csvHeader = []string{"id", "name", "market", "industry", "category", "listed_on", "isin"}
+
+// Fetch downloads and parses the ISIN page of market. The page is Big5-encoded.
+func Fetch(ctx context.Context, client *http.Client, baseURL, market string) ([]Company, error) {
+	idx := slices.IndexFunc(Markets, func(m Market) bool { return m.Name == market })
+	if idx < 0 {
+		return nil, fmt.Errorf("unknown market: %s", market)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprint(baseURL, Markets[idx].Mode), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s list: %w", market, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s list: %s", market, resp.Status)
+	}
+	return Parse(traditionalchinese.Big5.NewDecoder().Reader(resp.Body), market)
+}
+
+// Parse reads a UTF-8 ISIN page. Single-cell rows are section headings such
+// as 股票 or ETF; the following rows are securities whose first cell holds the
+// code and name separated by an ideographic space.
+func Parse(r io.Reader, market string) ([]Company, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		companies []Company
+		category  string
+	)
+	doc.Find("tr").Each(func(_ int, row *goquery.Selection) {
+		var cells []string
+		row.Find("td").Each(func(_ int, cell *goquery.Selection) {
+			cells = append(cells, strings.TrimSpace(cell.Text()))
+		})
+		switch {
+		case len(cells) == 1:
+			category = categoryName(cells[0])
+		case len(cells) >= 5:
+			id, name, ok := strings.Cut(cells[0], "　")
+			if !ok {
+				return // the column header row
+			}
+			companies = append(companies, Company{
+				ID:       strings.TrimSpace(id),
+				Name:     strings.TrimSpace(name),
+				Market:   market,
+				Industry: cells[4],
+				Category: category,
+				ListedOn: strings.ReplaceAll(cells[2], "/", "-"),
+				ISIN:     cells[1],
+			})
+		}
+	})
+	if len(companies) == 0 {
+		return nil, fmt.Errorf("no securities found in %s list", market)
+	}
+	return companies, nil
+}
+
+func categoryName(heading string) string {
+	heading = strings.TrimSpace(heading)
+	for name, prefix := range Categories {
+		if strings.HasPrefix(heading, prefix) {
+			return name
+		}
+	}
+	return heading
+}
+
+// FetchAll fetches the given markets and keeps the companies in the given
+// categories, sorted by ID.
+func FetchAll(
+	ctx context.Context,
+	client *http.Client,
+	baseURL string,
+	markets, categories []string,
+) ([]Company, error) {
+	var all []Company
+	for _, market := range markets {
+		companies, err := Fetch(ctx, client, baseURL, market)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range companies {
+			if slices.Contains(categories, c.Category) {
+				all = append(all, c)
+			}
+		}
+	}
+	slices.SortFunc(all, func(a, b Company) int { return strings.Compare(a.ID, b.ID) })
+	return all, nil
+}
+
+// WriteCSV writes the universe to path with a header row.
+func WriteCSV(path string, companies []Company) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write(csvHeader)
+	for _, c := range companies {
+		w.Write([]string{c.ID, c.Name, c.Market, c.Industry, c.Category, c.ListedOn, c.ISIN})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ReadCSV reads a universe file written by WriteCSV.
+func ReadCSV(path string) ([]Company, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = len(csvHeader)
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(records) == 0 || !slices.Equal(records[0], csvHeader) {
+		return nil, fmt.Errorf("%s is not a universe file", path)
+	}
+	companies := make([]Company, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		companies = append(companies, Company{
+			ID:       rec[0],
+			Name:     rec[1],
+			Market:   rec[2],
+			Industry: rec[3],
+			Category: rec[4],
+			ListedOn: rec[5],
+			ISIN:     rec[6],
+		})
+	}
+	return companies, nil
+}
+
+// WriteStockList writes the universe in the input stock list format read by
+// flow.LoadStockList, tagging each stock with its market and industry so
+// they can be selected with -group.
+func WriteStockList(path string, companies []Company, fetchedAt time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintf(
+		file,
+		"# Generated by \"scraper universe\" on %s; edits are overwritten.\n",
+		fetchedAt.Format("2006-01-02"),
+	)
+	fmt.Fprintln(file, "# id,name,groups (market;industry)")
+	// Names must not end the field or start a comment; groups must not
+	// contain a separator.
+	nameCleaner := strings.NewReplacer(",", " ", "#", " ")
+	groupCleaner := strings.NewReplacer(" ", "", ",", "", ";", "", "#", "")
+	for _, c := range companies {
+		groups := []string{c.Market}
+		if industry := groupCleaner.Replace(c.Industry); industry != "" {
+			groups = append(groups, industry)
+		}
+		name := nameCleaner.Replace(c.Name)
+		fmt.Fprintf(file, "%s,%s,%s\n", c.ID, name, strings.Join(groups, ";"))
+	}
+	return file.Close()
+}
+
+// Change is a company whose listing details differ between two universes.
+type Change struct {
+	Old, New Company
+}
+
+// Describe lists the fields that changed as "field: old -> new".
+func (c Change) Describe() []string {
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"name", c.Old.Name, c.New.Name},
+		{"market", c.Old.Market, c.New.Market},
+		{"industry", c.Old.Industry, c.New.Industry},
+		{"category", c.Old.Category, c.New.Category},
+		{"listed_on", c.Old.ListedOn, c.New.ListedOn},
+		{"isin", c.Old.ISIN, c.New.ISIN},
+	}
+	var changes []string
+	for _, f := range fields {
+		if f.old != f.new {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", f.name, f.old, f.new))
+		}
+	}
+	return changes
+}
+
+// Diff compares two universes by ID.
+type Diff struct {
+	Added   []Company
+	Removed []Company
+	Changed []Change
+}
+
+// Empty reports whether the universes are identical.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Compare returns the companies added to, removed from and changed in next
+// compared with prev. Results are ordered by ID.
+func Compare(prev, next []Company) Diff {
+	byID := func(companies []Company) map[string]Company {
+		m := make(map[string]Company, len(companies))
+		for _, c := range companies {
+			m[c.ID] = c
+		}
+		return m
+	}
+	prevByID, nextByID := byID(prev), byID(next)
+
+	var d Diff
+	for _, c := range next {
+		old, ok := prevByID[c.ID]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, c)
+		case old != c:
+			d.Changed = append(d.Changed, Change{Old: old, New: c})
+		}
+	}
+	for _, c := range prev {
+		if _, ok := nextByID[c.ID]; !ok {
+			d.Removed = append(d.Removed, c)
+		}
+	}
+	cmp := func(a, b Company) int { return strings.Compare(a.ID, b.ID) }
+	slices.SortFunc(d.Added, cmp)
+	slices.SortFunc(d.Removed, cmp)
+	slices.SortFunc(d.Changed, func(a, b Change) int { return cmp(a.New, b.New) })
+	return d
+}
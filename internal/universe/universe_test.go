@@ -0,0 +1,164 @@
+package universe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// isinPage mimics the layout of the TWSE ISIN pages.
+const isinPage = `<html><body>
+<table class='h4'>
+<tr align=center><td>有價證券代號及名稱 </td><td>國際證券辨識號碼(ISIN Code)</td><td>上市日</td><td>市場別</td><td>產業別</td><td>CFICode</td><td>備註</td></tr>
+<tr><td colspan=7><B> 股票 <B> </td></tr>
+<tr><td>1101　台泥</td><td>TW0001101004</td><td>1962/02/09</td><td>上市</td><td>水泥工業</td><td>ESVUFR</td><td></td></tr>
+<tr><td>2330　台積電</td><td>TW0002330008</td><td>1994/09/05</td><td>上市</td><td>半導體業</td><td>ESVUFR</td><td></td></tr>
+<tr><td colspan=7><B> ETF <B> </td></tr>
+<tr><td>0050　元大台灣50</td><td>TW0000050004</td><td>2003/06/30</td><td>上市</td><td></td><td>CEOGEU</td><td></td></tr>
+</table></body></html>`
+
+func TestParse(t *testing.T) {
+	companies, err := Parse(strings.NewReader(isinPage), "listed")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	want := []Company{
+		{"1101", "台泥", "listed", "水泥工業", "stock", "1962-02-09", "TW0001101004"},
+		{"2330", "台積電", "listed", "半導體業", "stock", "1994-09-05", "TW0002330008"},
+		{"0050", "元大台灣50", "listed", "", "etf", "2003-06-30", "TW0000050004"},
+	}
+	if !reflect.DeepEqual(companies, want) {
+		t.Fatalf("Parse() =\n%+v\nwant\n%+v", companies, want)
+	}
+
+	if _, err := Parse(strings.NewReader("<html></html>"), "listed"); err == nil {
+		t.Fatalf("expected error for a page without securities")
+	}
+}
+
+func TestFetchAll(t *testing.T) {
+	big5, err := traditionalchinese.Big5.NewEncoder().String(isinPage)
+	if err != nil {
+		t.Fatalf("failed to encode page: %v", err)
+	}
+	otcPage := strings.NewReplacer("1101　台泥", "6488　環球晶", "上市", "上櫃").Replace(isinPage)
+	otcBig5, err := traditionalchinese.Big5.NewEncoder().String(otcPage)
+	if err != nil {
+		t.Fatalf("failed to encode page: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("strMode") {
+		case "2":
+			w.Write([]byte(big5))
+		case "4":
+			w.Write([]byte(otcBig5))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	base := srv.URL + "/isin/C_public.jsp?strMode="
+	companies, err := FetchAll(
+		context.Background(),
+		srv.Client(),
+		base,
+		[]string{"listed", "otc"},
+		[]string{"stock"},
+	)
+	if err != nil {
+		t.Fatalf("FetchAll returned error: %v", err)
+	}
+	var ids []string
+	for _, c := range companies {
+		ids = append(ids, c.ID+"/"+c.Market)
+	}
+	want := []string{"1101/listed", "2330/listed", "2330/otc", "6488/otc"}
+	if !reflect.DeepEqual(ids, want) {
+		t.Fatalf("FetchAll() ids = %v, want %v", ids, want)
+	}
+	if companies[0].Name != "台泥" {
+		t.Fatalf("expected Big5 names to be decoded, got %q", companies[0].Name)
+	}
+
+	_, err = FetchAll(context.Background(), srv.Client(), base, []string{"emerging"}, []string{"stock"})
+	if err == nil {
+		t.Fatalf("expected error for a missing page")
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	companies, err := Parse(strings.NewReader(isinPage), "listed")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "universe.csv")
+	if err := WriteCSV(path, companies); err != nil {
+		t.Fatalf("WriteCSV returned error: %v", err)
+	}
+	got, err := ReadCSV(path)
+	if err != nil {
+		t.Fatalf("ReadCSV returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, companies) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got, companies)
+	}
+}
+
+func TestWriteStockList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "universe.txt")
+	companies := []Company{
+		{ID: "2330", Name: "台積電", Market: "listed", Industry: "半導體業"},
+		{ID: "0050", Name: "元大台灣50", Market: "listed"},
+	}
+	if err := WriteStockList(path, companies, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteStockList returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read stock list: %v", err)
+	}
+	for _, line := range []string{"on 2024-05-01", "2330,台積電,listed;半導體業\n", "0050,元大台灣50,listed\n"} {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("expected stock list to contain %q, got:\n%s", line, data)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	prev := []Company{
+		{ID: "1101", Name: "台泥", Market: "listed"},
+		{ID: "2330", Name: "台積電", Market: "listed"},
+		{ID: "6488", Name: "環球晶", Market: "emerging"},
+	}
+	next := []Company{
+		{ID: "2330", Name: "台積電", Market: "listed"},
+		{ID: "6488", Name: "環球晶", Market: "otc"},
+		{ID: "6669", Name: "緯穎", Market: "listed"},
+	}
+
+	d := Compare(prev, next)
+	if len(d.Added) != 1 || d.Added[0].ID != "6669" {
+		t.Errorf("unexpected added: %+v", d.Added)
+	}
+	if len(d.Removed) != 1 || d.Removed[0].ID != "1101" {
+		t.Errorf("unexpected removed: %+v", d.Removed)
+	}
+	if len(d.Changed) != 1 || d.Changed[0].Old.Market != "emerging" || d.Changed[0].New.Market != "otc" {
+		t.Errorf("unexpected changed: %+v", d.Changed)
+	} else if got := d.Changed[0].Describe(); !reflect.DeepEqual(got, []string{"market: emerging -> otc"}) {
+		t.Errorf("unexpected change description: %v", got)
+	}
+	if !Compare(next, next).Empty() {
+		t.Errorf("expected identical universes to have an empty diff")
+	}
+}